@@ -16,6 +16,10 @@ import (
 	"github.com/mdvorak/argo-application-operator/pkg/apis"
 	"github.com/mdvorak/argo-application-operator/pkg/argocd"
 	"github.com/mdvorak/argo-application-operator/pkg/controller"
+	"github.com/mdvorak/argo-application-operator/pkg/delivery"
+	"github.com/mdvorak/argo-application-operator/pkg/flux"
+	"github.com/mdvorak/argo-application-operator/pkg/report"
+	"github.com/mdvorak/argo-application-operator/pkg/webhook"
 	"github.com/mdvorak/argo-application-operator/version"
 
 	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
@@ -73,12 +77,53 @@ func main() {
 
 	printVersion()
 
-	// Argo namespace needs to be watched as well
-	err := argocd.AddNamespaceToWatched()
+	backend, err := delivery.GetBackend()
 	if err != nil {
-		log.Error(err, "Failed to add argo namespace to watched namespace list")
+		log.Error(err, "Failed to get delivery backend")
 		os.Exit(1)
 	}
+	log.Info(fmt.Sprintf("Delivery backend '%s'", backend))
+
+	// Target namespace needs to be watched as well
+	switch backend {
+	case delivery.BackendArgoCD:
+		kubeconfigSecret, err := argocd.GetKubeconfigSecret()
+		if err != nil {
+			log.Error(err, "Failed to get argo kubeconfig secret")
+			os.Exit(1)
+		}
+
+		// In hub mode, argo namespace is in the remote cluster
+		if kubeconfigSecret == nil {
+			if err := argocd.AddNamespaceToWatched(); err != nil {
+				log.Error(err, "Failed to add argo namespace to watched namespace list")
+				os.Exit(1)
+			}
+		} else {
+			log.Info(fmt.Sprintf("Argo running in remote cluster, using kubeconfig from secret '%s'", kubeconfigSecret))
+		}
+
+		instances, err := argocd.GetInstances()
+		if err != nil {
+			log.Error(err, "Failed to get argo instances")
+			os.Exit(1)
+		}
+		for _, instance := range instances {
+			log.Info(fmt.Sprintf("Argo instance '%s' in namespace '%s'", instance.Name, instance.Namespace))
+		}
+	case delivery.BackendFlux:
+		if err := flux.AddNamespaceToWatched(); err != nil {
+			log.Error(err, "Failed to add flux namespace to watched namespace list")
+			os.Exit(1)
+		}
+
+		fluxNamespace, err := flux.GetNamespace()
+		if err != nil {
+			log.Error(err, "Failed to get flux namespace")
+			os.Exit(1)
+		}
+		log.Info(fmt.Sprintf("Flux namespace '%s'", fluxNamespace))
+	}
 
 	namespace, err := k8sutil.GetWatchNamespace()
 	if err != nil {
@@ -87,13 +132,6 @@ func main() {
 	}
 	log.Info(fmt.Sprintf("Watch namespace '%s'", namespace))
 
-	argoNamespace, err := argocd.GetNamespace()
-	if err != nil {
-		log.Error(err, "Failed to get argo namespace")
-		os.Exit(1)
-	}
-	log.Info(fmt.Sprintf("Argo namespace '%s'", argoNamespace))
-
 	// Get a config to talk to the apiserver
 	cfg, err := config.GetConfig()
 	if err != nil {
@@ -115,6 +153,20 @@ func main() {
 		MetricsBindAddress: fmt.Sprintf("%s:%d", metricsHost, metricsPort),
 	}
 
+	webhookEnabled, err := webhook.GetEnabled()
+	if err != nil {
+		log.Error(err, "Failed to get webhook configuration")
+		os.Exit(1)
+	}
+	if webhookEnabled {
+		options.Port, err = webhook.GetPort()
+		if err != nil {
+			log.Error(err, "Failed to get webhook port")
+			os.Exit(1)
+		}
+		options.CertDir = webhook.GetCertDir()
+	}
+
 	// Add support for MultiNamespace set in WATCH_NAMESPACE (e.g ns1,ns2)
 	// Note that this is not intended to be used for excluding namespaces, this is better done via a Predicate
 	// Also note that you may face performance issues when using this with a high number of namespaces.
@@ -151,6 +203,27 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Setup all Webhooks
+	if webhookEnabled {
+		if err := webhook.AddToManager(mgr); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
+	// Setup report endpoint
+	reportEnabled, err := report.GetEnabled()
+	if err != nil {
+		log.Error(err, "Failed to get report configuration")
+		os.Exit(1)
+	}
+	if reportEnabled {
+		if err := report.AddToManager(mgr); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
 	// Add the Metrics Service
 	addMetrics(ctx, cfg)
 
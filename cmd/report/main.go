@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/mdvorak/argo-application-operator/pkg/report"
+
+	"github.com/spf13/pflag"
+)
+
+// Writes the report served by the operator report endpoint into a file, authorized by REPORT_TOKEN env var
+func main() {
+	url := pflag.StringP("url", "u", fmt.Sprintf("http://localhost:%d%s", report.PortDefault, report.ReportPath), "URL of the operator report endpoint")
+	output := pflag.StringP("output", "o", "-", "File the report is written to, - for stdout")
+	pflag.Parse()
+
+	if err := run(*url, *output); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(url, output string) error {
+	token, err := report.GetToken()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("invalid url %s: %w", url, err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := (&http.Client{Timeout: time.Minute}).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to get report: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("failed to get report: %s: %s", resp.Status, body)
+	}
+
+	r := &report.Report{}
+	if err := json.NewDecoder(resp.Body).Decode(r); err != nil {
+		return fmt.Errorf("invalid report: %w", err)
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize report: %w", err)
+	}
+	data = append(data, '\n')
+
+	if output == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err := ioutil.WriteFile(output, data, 0644); err != nil {
+		return fmt.Errorf("failed to write report to %s: %w", output, err)
+	}
+	return nil
+}
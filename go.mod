@@ -8,12 +8,15 @@ require (
 	github.com/go-logr/logr v0.1.0
 	github.com/kballard/go-shellquote v0.0.0-20180428030007-95032a82bc51 // indirect
 	github.com/operator-framework/operator-sdk v0.17.0
+	github.com/prometheus/client_golang v1.5.1
+	github.com/robfig/cron v0.0.0-20170526150127-736158dc09e1
 	github.com/spf13/pflag v1.0.5
 	gopkg.in/src-d/go-git.v4 v4.13.1 // indirect
 	k8s.io/api v0.17.4
 	k8s.io/apimachinery v0.17.4
 	k8s.io/client-go v12.0.0+incompatible
 	sigs.k8s.io/controller-runtime v0.5.2
+	sigs.k8s.io/yaml v1.1.0
 )
 
 replace (
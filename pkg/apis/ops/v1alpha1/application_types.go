@@ -2,7 +2,6 @@ package v1alpha1
 
 import (
 	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
-	"github.com/operator-framework/operator-sdk/pkg/status"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
@@ -12,6 +11,22 @@ import (
 
 const KindApplication = "Application"
 
+// Annotations set by the admission webhook, recording the user who was allowed to deploy into the destination,
+// and users who created and last modified the spec, and who paused updates
+const (
+	ApprovedByAnnotation     = "application.ops.csas.cz/approved-by"
+	ApprovedGroupsAnnotation = "application.ops.csas.cz/approved-groups"
+	CreatedByAnnotation      = "application.ops.csas.cz/created-by"
+	ModifiedByAnnotation     = "application.ops.csas.cz/modified-by"
+	PausedByAnnotation       = "application.ops.csas.cz/paused-by"
+)
+
+// Annotation which pauses updates of the target objects, its value should identify who paused it
+const PausedAnnotation = "application.ops.csas.cz/paused"
+
+// Annotations managed by the admission webhook, they cannot be set by users
+var WebhookAnnotations = []string{ApprovedByAnnotation, ApprovedGroupsAnnotation, CreatedByAnnotation, ModifiedByAnnotation, PausedByAnnotation}
+
 // ApplicationSpec defines the desired state of Application
 type ApplicationSpec struct {
 	// Source is a reference to the location ksonnet application definition
@@ -22,14 +37,91 @@ type ApplicationSpec struct {
 	IgnoreDifferences []argocdv1alpha1.ResourceIgnoreDifferences `json:"ignoreDifferences,omitempty"`
 	// Infos contains a list of useful information (URLs, email addresses, and plain text) that relates to the application
 	Info []argocdv1alpha1.Info `json:"info,omitempty"`
+	// TTL is a time to live of the application, counted from its creation. When it passes, the application is deleted.
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+	// ExpiresAt is an absolute time after which the application is deleted. When TTL is set as well, sooner one is used.
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// DependsOn is a list of names of other applications in the same namespace, which must be synced and healthy
+	// before this application is synced automatically
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// SyncWindows control when the application can be synced, they are rendered into the namespace AppProject
+	SyncWindows []SyncWindow `json:"syncWindows,omitempty"`
+	// Clusters the application is deployed to. When set, an ApplicationSet is generated instead of a single Application.
+	Clusters *ApplicationClusters `json:"clusters,omitempty"`
+	// Destination of the application. Defaults to the namespace of the application.
+	Destination *ApplicationDestination `json:"destination,omitempty"`
+	// Notifications are subscriptions of argocd-notifications triggers, rendered as annotations of the generated application
+	Notifications []NotificationSubscription `json:"notifications,omitempty"`
+}
+
+// NotificationSubscription defines recipients of a notification trigger
+type NotificationSubscription struct {
+	// Trigger name, e.g. on-sync-failed. It must be allowed by cluster admins.
+	Trigger string `json:"trigger"`
+	// Service name, e.g. slack. It must be allowed by cluster admins.
+	Service string `json:"service"`
+	// Recipients, e.g. slack channels or email addresses
+	// +kubebuilder:validation:MinItems=1
+	Recipients []string `json:"recipients"`
+}
+
+// ApplicationDestination defines where the application is deployed to
+type ApplicationDestination struct {
+	// Namespace to deploy into, it must be owned by the same tenant as the namespace of the application
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ApplicationClusters defines clusters registered in Argo CD, either by their names, or by a label selector
+type ApplicationClusters struct {
+	// Names of the clusters, as registered in Argo CD
+	Names []string `json:"names,omitempty"`
+	// Selector of the clusters, matching labels of their Argo CD cluster secrets
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// SyncWindow defines a time window in which syncs are allowed or denied
+type SyncWindow struct {
+	// Kind defines if the window allows or blocks syncs
+	// +kubebuilder:validation:Enum=allow;deny
+	Kind string `json:"kind"`
+	// Schedule is the time the window will begin, specified in cron format
+	Schedule string `json:"schedule"`
+	// Duration is the amount of time the sync window will be open, e.g. 1h
+	Duration string `json:"duration"`
+	// TimeZone of the schedule, e.g. Europe/Prague. Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+	// ManualSync enables manual syncs when they would otherwise be blocked
+	ManualSync bool `json:"manualSync,omitempty"`
 }
 
 // ApplicationStatus defines the observed state of Application
 type ApplicationStatus struct {
+	// ObservedGeneration is the generation of the spec, which was reconciled last
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastReconciledAt is the time of the last reconciliation
+	LastReconciledAt *metav1.Time `json:"lastReconciledAt,omitempty"`
 	// Conditions represent the latest available observations of an object's state
-	Conditions status.Conditions `json:"conditions,omitempty"`
+	Conditions Conditions `json:"conditions,omitempty"`
+	// Summary is a short human readable summary of the application state, e.g. Ready or Degraded: NameConflict
+	Summary string `json:"summary,omitempty"`
 	// References to created objects
 	References References `json:"references,omitempty"`
+	// Sync status of the managed application, as reported by Argo CD
+	SyncStatus argocdv1alpha1.SyncStatusCode `json:"syncStatus,omitempty"`
+	// Health status of the managed application, as reported by Argo CD
+	HealthStatus argocdv1alpha1.HealthStatusCode `json:"healthStatus,omitempty"`
+	// Revision of the source, which is synced to the managed application, as reported by Argo CD
+	SyncedRevision string `json:"syncedRevision,omitempty"`
+	// State of sync windows, present only when the application has some
+	SyncWindow *SyncWindowStatus `json:"syncWindow,omitempty"`
+}
+
+// SyncWindowStatus defines observed state of sync windows
+type SyncWindowStatus struct {
+	// SyncAllowed is true when automated sync is currently allowed by sync windows
+	SyncAllowed bool `json:"syncAllowed"`
+	// NextWindowAt is the time when the next sync window opens
+	NextWindowAt *metav1.Time `json:"nextWindowAt,omitempty"`
 }
 
 // Reference defines managed object
@@ -80,6 +172,11 @@ func (a *References) RemoveReference(r Reference) bool {
 
 // Create Reference object from Application.argocd.io
 func ReferenceFromApplication(obj *argocdv1alpha1.Application, scheme *runtime.Scheme) (Reference, error) {
+	return ReferenceFromObject(obj, obj, scheme)
+}
+
+// Create Reference object from any object, typically passed as both arguments
+func ReferenceFromObject(obj runtime.Object, meta metav1.Object, scheme *runtime.Scheme) (Reference, error) {
 	gvk, err := apiutil.GVKForObject(obj, scheme)
 	if err != nil {
 		return Reference{}, err
@@ -88,8 +185,8 @@ func ReferenceFromApplication(obj *argocdv1alpha1.Application, scheme *runtime.S
 	return Reference{
 		APIVersion: gvk.GroupVersion().String(),
 		Kind:       gvk.Kind,
-		Name:       obj.Name,
-		Namespace:  obj.Namespace,
+		Name:       meta.GetName(),
+		Namespace:  meta.GetNamespace(),
 	}, nil
 }
 
@@ -98,6 +195,14 @@ func ReferenceFromApplication(obj *argocdv1alpha1.Application, scheme *runtime.S
 // Application is the Schema for the applications API
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:path=applications,scope=Namespaced
+// +kubebuilder:printcolumn:name="Repo",type=string,JSONPath=`.spec.source.repoURL`,priority=1,description="Repository URL of the source"
+// +kubebuilder:printcolumn:name="Revision",type=string,JSONPath=`.spec.source.targetRevision`,priority=1,description="Target revision of the source"
+// +kubebuilder:printcolumn:name="Target",type=string,JSONPath=`.status.references[?(@.kind=="Application")].name`,priority=1,description="Name of the generated Argo CD application"
+// +kubebuilder:printcolumn:name="Synced",type=string,JSONPath=`.status.syncStatus`,description="Sync status of the generated application"
+// +kubebuilder:printcolumn:name="Healthy",type=string,JSONPath=`.status.healthStatus`,description="Health status of the generated application"
+// +kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`,description="Result of the last reconciliation"
+// +kubebuilder:printcolumn:name="Summary",type=string,JSONPath=`.status.summary`,description="Short summary of the application state"
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type Application struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -115,6 +220,14 @@ type ApplicationList struct {
 	Items           []Application `json:"items"`
 }
 
+// Returns namespace the application is deployed into
+func (in *Application) DestinationNamespace() string {
+	if in.Spec.Destination != nil && len(in.Spec.Destination.Namespace) > 0 {
+		return in.Spec.Destination.Namespace
+	}
+	return in.Namespace
+}
+
 func init() {
 	SchemeBuilder.Register(&Application{}, &ApplicationList{})
 }
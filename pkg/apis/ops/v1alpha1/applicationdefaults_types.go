@@ -0,0 +1,46 @@
+package v1alpha1
+
+import (
+	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+const KindApplicationDefaults = "ApplicationDefaults"
+
+// ApplicationDefaultsSpec defines default values of ApplicationSpec
+type ApplicationDefaultsSpec struct {
+	// SyncPolicy controls when a sync will be performed
+	SyncPolicy *argocdv1alpha1.SyncPolicy `json:"syncPolicy,omitempty"`
+	// IgnoreDifferences controls resources fields which should be ignored during comparison
+	IgnoreDifferences []argocdv1alpha1.ResourceIgnoreDifferences `json:"ignoreDifferences,omitempty"`
+	// Infos contains a list of useful information (URLs, email addresses, and plain text) that relates to the application
+	Info []argocdv1alpha1.Info `json:"info,omitempty"`
+	// TargetRevision is a default revision of the application source
+	TargetRevision string `json:"targetRevision,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ApplicationDefaults defines default values of Application objects in its namespace
+// +kubebuilder:resource:path=applicationdefaults,scope=Namespaced
+type ApplicationDefaults struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ApplicationDefaultsSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ApplicationDefaultsList contains a list of ApplicationDefaults
+type ApplicationDefaultsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ApplicationDefaults `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ApplicationDefaults{}, &ApplicationDefaultsList{})
+}
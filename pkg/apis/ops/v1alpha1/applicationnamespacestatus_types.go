@@ -0,0 +1,72 @@
+package v1alpha1
+
+import (
+	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+const KindApplicationNamespaceStatus = "ApplicationNamespaceStatus"
+
+// Name of the single ApplicationNamespaceStatus object in each namespace with applications
+const ApplicationNamespaceStatusName = "applications"
+
+// ApplicationNamespaceStatusStatus defines observed state of all applications in the namespace
+type ApplicationNamespaceStatusStatus struct {
+	// Applications in the namespace, sorted by name
+	Applications []ApplicationSummary `json:"applications,omitempty"`
+	// Total number of applications
+	Total int `json:"total"`
+	// Number of applications, which are ready
+	Ready int `json:"ready"`
+	// Number of applications, which are progressing
+	Progressing int `json:"progressing"`
+	// Number of applications, which are degraded
+	Degraded int `json:"degraded"`
+}
+
+// ApplicationSummary defines observed state of a single application
+type ApplicationSummary struct {
+	// Name of the Application.ops.csas.cz
+	Name string `json:"name"`
+	// Name of the generated Argo CD application
+	Target string `json:"target,omitempty"`
+	// Sync status of the generated application
+	SyncStatus argocdv1alpha1.SyncStatusCode `json:"syncStatus,omitempty"`
+	// Health status of the generated application
+	HealthStatus argocdv1alpha1.HealthStatusCode `json:"healthStatus,omitempty"`
+	// Summary of the application state
+	Summary string `json:"summary,omitempty"`
+	// Error of the last reconciliation, empty when it was successful
+	LastError string `json:"lastError,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ApplicationNamespaceStatus summarizes all Application objects in its namespace, it is maintained by the operator
+// +kubebuilder:resource:path=applicationnamespacestatuses,scope=Namespaced
+// +kubebuilder:printcolumn:name="Total",type=integer,JSONPath=`.status.total`
+// +kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.ready`
+// +kubebuilder:printcolumn:name="Progressing",type=integer,JSONPath=`.status.progressing`
+// +kubebuilder:printcolumn:name="Degraded",type=integer,JSONPath=`.status.degraded`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type ApplicationNamespaceStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status ApplicationNamespaceStatusStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ApplicationNamespaceStatusList contains a list of ApplicationNamespaceStatus
+type ApplicationNamespaceStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ApplicationNamespaceStatus `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ApplicationNamespaceStatus{}, &ApplicationNamespaceStatusList{})
+}
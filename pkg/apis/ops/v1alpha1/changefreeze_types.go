@@ -0,0 +1,46 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
+
+const KindChangeFreeze = "ChangeFreeze"
+
+// ChangeFreezeSpec defines the desired state of ChangeFreeze
+type ChangeFreezeSpec struct {
+	// Reason of the freeze, it is shown in conditions of affected applications
+	Reason string `json:"reason,omitempty"`
+	// Until is a time when the freeze ends. When omitted, it lasts until the object is deleted.
+	Until *metav1.Time `json:"until,omitempty"`
+}
+
+// Returns true when the freeze is in effect at given time
+func (in *ChangeFreeze) IsActive(now metav1.Time) bool {
+	return in.DeletionTimestamp == nil && (in.Spec.Until == nil || now.Before(in.Spec.Until))
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ChangeFreeze stops automated sync of all applications in the cluster while it exists
+// +kubebuilder:resource:path=changefreezes,scope=Cluster
+type ChangeFreeze struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ChangeFreezeSpec `json:"spec,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ChangeFreezeList contains a list of ChangeFreeze
+type ChangeFreezeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ChangeFreeze `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ChangeFreeze{}, &ChangeFreezeList{})
+}
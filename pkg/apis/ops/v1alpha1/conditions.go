@@ -0,0 +1,80 @@
+package v1alpha1
+
+import (
+	"github.com/operator-framework/operator-sdk/pkg/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Conditions summarizing state of Application and ApplicationNamespaceStatus
+const (
+	// Target objects exist and are managed by the operator
+	AvailableCondition status.ConditionType = "Available"
+	// Target objects are reconciled, synced and healthy
+	ReadyCondition status.ConditionType = "Ready"
+	// Reconciliation failed, or target objects are degraded
+	DegradedCondition status.ConditionType = "Degraded"
+	// Target objects are reconciled, and they are being synced or becoming healthy
+	ProgressingCondition status.ConditionType = "Progressing"
+)
+
+// Reason of conditions of an object being deleted
+const DeletedReason status.ConditionReason = "Deleted"
+
+// Condition is an observation of the object state, made for a specific generation of its spec
+type Condition struct {
+	status.Condition `json:",inline"`
+	// ObservedGeneration is the generation of the spec, which the condition was observed for
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// Array of Condition objects, unique by their type
+type Conditions []Condition
+
+// Returns true when the condition of given type exists and is true
+func (c Conditions) IsTrueFor(t status.ConditionType) bool {
+	cond := c.GetCondition(t)
+	return cond != nil && cond.IsTrue()
+}
+
+// Returns condition of given type, nil when there is none
+func (c Conditions) GetCondition(t status.ConditionType) *Condition {
+	for i := range c {
+		if c[i].Type == t {
+			return &c[i]
+		}
+	}
+	return nil
+}
+
+// Add or replace condition of the same type, observed for given generation. LastTransitionTime is kept when status
+// does not change. Returns true if there was any change.
+func (c *Conditions) SetCondition(newCond status.Condition, generation int64) bool {
+	cond := Condition{Condition: newCond, ObservedGeneration: generation}
+	cond.LastTransitionTime = metav1.Now()
+
+	if existing := c.GetCondition(newCond.Type); existing != nil {
+		if existing.Status == cond.Status {
+			cond.LastTransitionTime = existing.LastTransitionTime
+		}
+		changed := existing.Status != cond.Status ||
+			existing.Reason != cond.Reason ||
+			existing.Message != cond.Message ||
+			existing.ObservedGeneration != cond.ObservedGeneration
+		*existing = cond
+		return changed
+	}
+
+	*c = append(*c, cond)
+	return true
+}
+
+// Remove condition of given type, returns true if it existed
+func (c *Conditions) RemoveCondition(t status.ConditionType) bool {
+	for i, cond := range *c {
+		if cond.Type == t {
+			*c = append((*c)[:i], (*c)[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
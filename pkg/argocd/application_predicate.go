@@ -7,7 +7,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
-// Detect update only when object changes, ignores Status
+// Detect update only when object changes, ignores Status except sync and health status codes, and synced revision
 type ApplicationUpdatedPredicate struct {
 	predicate.Predicate
 }
@@ -18,8 +18,11 @@ func (p ApplicationUpdatedPredicate) Update(e event.UpdateEvent) bool {
 	objOld := e.ObjectOld.(*v1alpha1.Application)
 
 	// Compare what we are interested in
-	// NOTE we need to ignore Status! Argo updates it every 5 secs
-	return !reflect.DeepEqual(objNew.Labels, objOld.Labels) ||
+	// NOTE we need to ignore most of the Status! Argo updates it every 5 secs
+	return objNew.Status.Sync.Status != objOld.Status.Sync.Status ||
+		objNew.Status.Sync.Revision != objOld.Status.Sync.Revision ||
+		objNew.Status.Health.Status != objOld.Status.Health.Status ||
+		!reflect.DeepEqual(objNew.Labels, objOld.Labels) ||
 		!reflect.DeepEqual(objNew.Annotations, objOld.Annotations) ||
 		!reflect.DeepEqual(objNew.Spec, objOld.Spec) ||
 		!reflect.DeepEqual(objNew.Operation, objOld.Operation) ||
@@ -3,9 +3,11 @@ package argocd
 import (
 	"errors"
 	"fmt"
+	"github.com/mdvorak/argo-application-operator/pkg/delivery"
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	"k8s.io/apimachinery/pkg/types"
 	"os"
-	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"strconv"
 	"strings"
 )
 
@@ -16,6 +18,10 @@ const (
 	DestinationServerDefault = "https://kubernetes.default.svc"
 	ControllerServiceAccount = "argocd-application-controller"
 	ServerServiceAccount     = "argocd-server"
+	ApplicationSetsEnvVar    = "ARGOCD_APPLICATIONSETS"
+	KubeconfigSecretEnvVar   = "ARGOCD_KUBECONFIG_SECRET"
+	ClusterNameEnvVar        = "ARGOCD_CLUSTER_NAME"
+	ClusterServerEnvVar      = "ARGOCD_CLUSTER_SERVER"
 )
 
 func GetDestinationServer() string {
@@ -35,40 +41,59 @@ func GetNamespace() (string, error) {
 	}
 }
 
-func AddNamespaceToWatched() error {
-	log := logf.Log.WithName("argocd_env")
+// Returns true when ApplicationSet controller is installed, and ApplicationSets can be generated
+func GetApplicationSetsEnabled() (bool, error) {
+	if value, ok := os.LookupEnv(ApplicationSetsEnvVar); ok && len(value) > 0 {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, fmt.Errorf("%s is not a valid boolean: %w", ApplicationSetsEnvVar, err)
+		}
+		return enabled, nil
+	} else {
+		// Default
+		return false, nil
+	}
+}
 
-	argoNamespace, ok := os.LookupEnv(NamespaceEnvVar)
-	if !ok {
-		return errors.New(fmt.Sprintf("%s not set, cannot add it as watched namespace", NamespaceEnvVar))
+// Returns Secret with kubeconfig of a remote cluster running Argo CD, nil when Argo CD runs in the local cluster.
+// Value is either namespace/name, or just name of the Secret in the operator namespace.
+func GetKubeconfigSecret() (*types.NamespacedName, error) {
+	value, ok := os.LookupEnv(KubeconfigSecretEnvVar)
+	if !ok || len(value) == 0 {
+		return nil, nil
 	}
 
-	watchNamespace, ok := os.LookupEnv(k8sutil.WatchNamespaceEnvVar)
-	// Empty string means everything is watched
-	if !ok || len(watchNamespace) == 0 {
-		return nil
+	if i := strings.Index(value, "/"); i >= 0 {
+		return &types.NamespacedName{Namespace: value[:i], Name: value[i+1:]}, nil
 	}
 
-	// Add to env variable
-	if !contains(strings.Split(watchNamespace, ","), argoNamespace) {
-		// Set env var
-		if err := os.Setenv(k8sutil.WatchNamespaceEnvVar, watchNamespace+","+argoNamespace); err != nil {
-			// Failed
-			return err
-		} else {
-			// OK
-			log.Info(fmt.Sprintf("%s '%s' is not part of %s, forcefully added", NamespaceEnvVar, argoNamespace, k8sutil.WatchNamespaceEnvVar))
-		}
+	namespace, err := k8sutil.GetOperatorNamespace()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get operator namespace for %s: %w", KubeconfigSecretEnvVar, err)
 	}
+	return &types.NamespacedName{Namespace: namespace, Name: value}, nil
+}
 
-	return nil
+// Returns name of the local cluster, as registered in remote Argo CD
+func GetClusterName() (string, error) {
+	if value, ok := os.LookupEnv(ClusterNameEnvVar); ok && len(value) > 0 {
+		return value, nil
+	} else {
+		return "", errors.New(fmt.Sprintf("%s not set", ClusterNameEnvVar))
+	}
 }
 
-func contains(list []string, s string) bool {
-	for _, v := range list {
-		if v == s {
-			return true
-		}
+// Returns server URL of the local cluster, as registered in remote Argo CD. When empty, it is looked up
+// in the cluster secrets of Argo CD, which requires access to them.
+func GetClusterServer() string {
+	return os.Getenv(ClusterServerEnvVar)
+}
+
+// Adds namespaces of all Argo CD instances to the watched namespaces
+func AddNamespaceToWatched() error {
+	instances, err := GetInstances()
+	if err != nil {
+		return err
 	}
-	return false
+	return delivery.AddNamespacesToWatched(InstanceNamespaces(instances))
 }
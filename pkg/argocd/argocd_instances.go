@@ -0,0 +1,102 @@
+package argocd
+
+import (
+	"fmt"
+	"os"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+//noinspection GoUnusedConst
+const (
+	InstancesEnvVar = "ARGOCD_INSTANCES"
+	// Label of the namespace, selecting Argo CD instance by its name
+	InstanceLabel = "application.ops.csas.cz/argocd-instance"
+	// Placeholder of the project template, replaced by namespace of the Application.ops.csas.cz
+	ProjectNamespacePlaceholder = "{namespace}"
+	InstanceDefaultName         = "default"
+)
+
+// Argo CD instance, into which applications are generated
+type Instance struct {
+	// Name of the instance, referenced by the namespace label
+	Name string `json:"name"`
+	// Namespace Argo CD runs in
+	Namespace string `json:"namespace"`
+	// Server of the generated applications destination
+	DestinationServer string `json:"destinationServer,omitempty"`
+	// Name of the project of generated applications, where {namespace} is replaced by namespace of the source object
+	Project string `json:"project,omitempty"`
+}
+
+// Returns project name for given namespace
+func (i *Instance) ProjectName(namespace string) string {
+	return strings.ReplaceAll(i.Project, ProjectNamespacePlaceholder, namespace)
+}
+
+// Returns configured Argo CD instances, first being the default one. When ARGOCD_INSTANCES is not set,
+// single instance is configured by ARGOCD_NAMESPACE and ARGOCD_DESTINATION_SERVER env vars.
+func GetInstances() ([]Instance, error) {
+	value, ok := os.LookupEnv(InstancesEnvVar)
+	if !ok || len(value) == 0 {
+		namespace, err := GetNamespace()
+		if err != nil {
+			return nil, err
+		}
+		return []Instance{{
+			Name:              InstanceDefaultName,
+			Namespace:         namespace,
+			DestinationServer: GetDestinationServer(),
+			Project:           ProjectNamespacePlaceholder,
+		}}, nil
+	}
+
+	var instances []Instance
+	if err := yaml.Unmarshal([]byte(value), &instances); err != nil {
+		return nil, fmt.Errorf("%s is not a valid list of instances: %w", InstancesEnvVar, err)
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("%s must contain at least one instance", InstancesEnvVar)
+	}
+
+	names := make(map[string]bool, len(instances))
+	for i := range instances {
+		instance := &instances[i]
+		if len(instance.Name) == 0 || len(instance.Namespace) == 0 {
+			return nil, fmt.Errorf("%s instance %d must have name and namespace", InstancesEnvVar, i)
+		}
+		if names[instance.Name] {
+			return nil, fmt.Errorf("%s instance %s is defined more than once", InstancesEnvVar, instance.Name)
+		}
+		names[instance.Name] = true
+
+		// Defaults
+		if len(instance.DestinationServer) == 0 {
+			instance.DestinationServer = GetDestinationServer()
+		}
+		if len(instance.Project) == 0 {
+			instance.Project = ProjectNamespacePlaceholder
+		}
+	}
+	return instances, nil
+}
+
+// Returns namespaces of all instances
+func InstanceNamespaces(instances []Instance) []string {
+	namespaces := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		if !contains(namespaces, instance.Namespace) {
+			namespaces = append(namespaces, instance.Namespace)
+		}
+	}
+	return namespaces
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package argocd
+
+import (
+	"context"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+const (
+	KubeconfigSecretKey      = "kubeconfig"
+	ClusterSecretTypeLabel   = "argocd.argoproj.io/secret-type"
+	ClusterSecretTypeCluster = "cluster"
+	clusterSecretNameField   = "name"
+	clusterSecretServerField = "server"
+)
+
+// Remote cluster running Argo CD, accessed through kubeconfig
+type RemoteCluster struct {
+	// Client reading from the Cache
+	Client client.Client
+	// Cache of the Argo CD namespaces, must be started, e.g. by adding it to the manager
+	Cache cache.Cache
+	// Reader reading directly from the API server
+	Reader client.Reader
+}
+
+// Connects to the remote cluster using kubeconfig stored in given Secret. Cache is limited to given namespaces.
+func NewRemoteCluster(ctx context.Context, reader client.Reader, secretName types.NamespacedName, scheme *runtime.Scheme, namespaces []string) (*RemoteCluster, error) {
+	secret := &corev1.Secret{}
+	if err := reader.Get(ctx, secretName, secret); err != nil {
+		return nil, fmt.Errorf("failed to get kubeconfig Secret %s: %w", secretName, err)
+	}
+	kubeconfig, ok := secret.Data[KubeconfigSecretKey]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig Secret %s does not contain %s key", secretName, KubeconfigSecretKey)
+	}
+
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig from Secret %s: %w", secretName, err)
+	}
+	mapper, err := apiutil.NewDynamicRESTMapper(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create remote REST mapper: %w", err)
+	}
+
+	newCache := cache.New
+	if len(namespaces) > 1 {
+		newCache = cache.MultiNamespacedCacheBuilder(namespaces)
+	}
+	remoteCache, err := newCache(cfg, cache.Options{Scheme: scheme, Mapper: mapper, Namespace: namespaces[0]})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create remote cache: %w", err)
+	}
+	remoteClient, err := client.New(cfg, client.Options{Scheme: scheme, Mapper: mapper})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create remote client: %w", err)
+	}
+
+	return &RemoteCluster{
+		Client: &client.DelegatingClient{
+			Reader:       &client.DelegatingReader{CacheReader: remoteCache, ClientReader: remoteClient},
+			Writer:       remoteClient,
+			StatusClient: remoteClient,
+		},
+		Cache:  remoteCache,
+		Reader: remoteClient,
+	}, nil
+}
+
+// Returns server URL of the cluster registered in Argo CD under given name
+func (rc *RemoteCluster) ClusterServer(ctx context.Context, namespace, name string) (string, error) {
+	secrets := &corev1.SecretList{}
+	err := rc.Reader.List(ctx, secrets, client.InNamespace(namespace), client.MatchingLabels{ClusterSecretTypeLabel: ClusterSecretTypeCluster})
+	if err != nil {
+		return "", fmt.Errorf("failed to list Argo CD clusters: %w", err)
+	}
+
+	for _, s := range secrets.Items {
+		if string(s.Data[clusterSecretNameField]) == name {
+			return string(s.Data[clusterSecretServerField]), nil
+		}
+	}
+	return "", fmt.Errorf("cluster \"%s\" is not registered in Argo CD", name)
+}
@@ -0,0 +1,10 @@
+package controller
+
+import (
+	"github.com/mdvorak/argo-application-operator/pkg/controller/applicationnamespacestatus"
+)
+
+func init() {
+	// AddToManagerFuncs is a list of functions to create controllers and add them to a manager.
+	AddToManagerFuncs = append(AddToManagerFuncs, applicationnamespacestatus.Add)
+}
@@ -0,0 +1,173 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/operator-framework/operator-sdk/pkg/status"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+// Field manager of server-side apply, the operator owns exactly the fields it sets
+const fieldManager = "application-operator"
+
+const conflictCondition = "Conflict"
+
+// Field manager of earlier versions of the operator, which created and updated target objects without server-side
+// apply. API server names such manager after the binary, from the user agent.
+var legacyFieldManager = strings.Split(rest.DefaultKubernetesUserAgent(), "/")[0]
+
+// Converts the object into apply configuration, without status and metadata populated by the API server
+func newApplyObject(obj runtime.Object, gvk schema.GroupVersionKind) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s to unstructured: %w", gvk.Kind, err)
+	}
+
+	applied := &unstructured.Unstructured{Object: content}
+	delete(applied.Object, "status")
+	unstructured.RemoveNestedField(applied.Object, "metadata", "creationTimestamp")
+	applied.SetGroupVersionKind(gvk)
+	return applied, nil
+}
+
+// Applies the object using server-side apply. Unless forced, ownership of fields managed by others is not taken,
+// and on conflict nothing is changed and Conflict condition is returned.
+func applyObject(ctx context.Context, c client.Client, obj *unstructured.Unstructured, force bool) (*status.Condition, error) {
+	kind := obj.GetKind() + "." + obj.GroupVersionKind().Group
+
+	opts := []client.PatchOption{client.FieldOwner(fieldManager)}
+	if force {
+		opts = append(opts, client.ForceOwnership)
+	}
+	err := c.Patch(ctx, obj, client.Apply, opts...)
+	if k8serrors.IsConflict(err) {
+		// Object might be created by earlier version of the operator, take over its fields once and try again
+		migrated, migrateErr := migrateLegacyFields(ctx, c, obj)
+		if migrateErr != nil {
+			return nil, migrateErr
+		}
+		if migrated {
+			err = c.Patch(ctx, obj, client.Apply, opts...)
+		}
+	}
+	if k8serrors.IsConflict(err) {
+		return &status.Condition{
+			Type:    conflictCondition,
+			Status:  corev1.ConditionTrue,
+			Reason:  "FieldManagerConflict",
+			Message: fmt.Sprintf("%s \"%s\" has fields modified by other managers, not overwriting them: %s", kind, obj.GetName(), err.Error()),
+		}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to apply %s: %w", kind, err)
+	}
+
+	return nil, nil
+}
+
+// Transfers ownership of fields managed by legacyFieldManager to fieldManager, so they are applied, or removed when
+// no longer set, without conflicts. Returns false when there was nothing to migrate.
+func migrateLegacyFields(ctx context.Context, c client.Client, obj *unstructured.Unstructured) (bool, error) {
+	kind := obj.GetKind() + "." + obj.GroupVersionKind().Group
+
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(obj.GroupVersionKind())
+	if err := c.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, live); err != nil {
+		return false, fmt.Errorf("failed to get %s for field manager migration: %w", kind, err)
+	}
+
+	legacy, applied := -1, -1
+	entries := live.GetManagedFields()
+	for i, entry := range entries {
+		switch {
+		case entry.Manager == legacyFieldManager && entry.Operation == metav1.ManagedFieldsOperationUpdate:
+			legacy = i
+		case entry.Manager == fieldManager && entry.Operation == metav1.ManagedFieldsOperationApply:
+			applied = i
+		}
+	}
+	if legacy < 0 {
+		return false, nil
+	}
+
+	// Status is never applied, it must stay with the controller reporting it
+	fields, err := decodeFields(entries[legacy].FieldsV1)
+	if err != nil {
+		return false, fmt.Errorf("invalid managed fields of %s: %w", kind, err)
+	}
+	delete(fields, "f:status")
+
+	if applied < 0 {
+		entry := entries[legacy]
+		entry.Manager = fieldManager
+		entry.Operation = metav1.ManagedFieldsOperationApply
+		entries[legacy] = entry
+		applied = legacy
+	} else {
+		owned, err := decodeFields(entries[applied].FieldsV1)
+		if err != nil {
+			return false, fmt.Errorf("invalid managed fields of %s: %w", kind, err)
+		}
+		fields = mergeFields(owned, fields)
+	}
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return false, fmt.Errorf("failed to serialize managed fields: %w", err)
+	}
+	entries[applied].FieldsType = "FieldsV1"
+	entries[applied].FieldsV1 = &metav1.FieldsV1{Raw: raw}
+
+	migrated := make([]metav1.ManagedFieldsEntry, 0, len(entries))
+	for i, entry := range entries {
+		if i != legacy || i == applied {
+			migrated = append(migrated, entry)
+		}
+	}
+
+	// Replace managed fields, unless the object has changed meanwhile
+	patch, err := json.Marshal([]map[string]interface{}{
+		{"op": "test", "path": "/metadata/resourceVersion", "value": live.GetResourceVersion()},
+		{"op": "replace", "path": "/metadata/managedFields", "value": migrated},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to serialize managed fields patch: %w", err)
+	}
+	if err := c.Patch(ctx, live, client.RawPatch(types.JSONPatchType, patch)); err != nil {
+		return false, fmt.Errorf("failed to migrate field manager of %s: %w", kind, err)
+	}
+	return true, nil
+}
+
+func decodeFields(fields *metav1.FieldsV1) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	if fields == nil || len(fields.Raw) == 0 {
+		return result, nil
+	}
+	if err := json.Unmarshal(fields.Raw, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Returns union of two field sets, in FieldsV1 trie format
+func mergeFields(into, from map[string]interface{}) map[string]interface{} {
+	for key, value := range from {
+		fromChild, ok := value.(map[string]interface{})
+		intoChild, exists := into[key].(map[string]interface{})
+		if ok && exists {
+			into[key] = mergeFields(intoChild, fromChild)
+		} else if !exists {
+			into[key] = value
+		}
+	}
+	return into
+}
@@ -0,0 +1,76 @@
+package application
+
+import (
+	"fmt"
+	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"github.com/operator-framework/operator-sdk/pkg/status"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Returns Ready, Degraded and Progressing conditions of the CR, after reconciliation with given result
+func newSummaryConditions(cr *opsv1alpha1.Application, available bool, err error) []status.Condition {
+	var ready, degraded, progressing bool
+	var reason status.ConditionReason
+	var message string
+
+	switch {
+	case err != nil:
+		degraded = true
+		reason, message = errorReason(err), err.Error()
+	case !available:
+		reason, message = opsv1alpha1.DeletedReason, "application is being deleted"
+	default:
+		message = fmt.Sprintf("sync status %s, health status %s", orUnknown(string(cr.Status.SyncStatus)), orUnknown(string(cr.Status.HealthStatus)))
+		switch {
+		case cr.Status.HealthStatus == argocdv1alpha1.HealthStatusDegraded:
+			degraded, reason = true, "TargetDegraded"
+		case cr.Status.SyncStatus == argocdv1alpha1.SyncStatusCodeSynced && cr.Status.HealthStatus == argocdv1alpha1.HealthStatusHealthy:
+			ready, reason = true, "SyncedAndHealthy"
+		case cr.Status.SyncStatus == argocdv1alpha1.SyncStatusCodeOutOfSync:
+			progressing, reason = true, "OutOfSync"
+		default:
+			progressing, reason = true, "WaitingForTarget"
+		}
+	}
+
+	return []status.Condition{
+		newSummaryCondition(opsv1alpha1.ReadyCondition, ready, reason, message),
+		newSummaryCondition(opsv1alpha1.DegradedCondition, degraded, reason, message),
+		newSummaryCondition(opsv1alpha1.ProgressingCondition, progressing, reason, message),
+	}
+}
+
+// Returns short summary of the application state, the true summary condition with its reason, e.g. Degraded: NameConflict
+func newSummary(conditions []status.Condition) string {
+	for _, cond := range conditions {
+		if cond.IsTrue() {
+			if cond.Type == opsv1alpha1.ReadyCondition {
+				return string(cond.Type)
+			}
+			return fmt.Sprintf("%s: %s", cond.Type, cond.Reason)
+		}
+	}
+	// None is true, application is being deleted
+	return string(conditions[0].Reason)
+}
+
+func newSummaryCondition(condType status.ConditionType, value bool, reason status.ConditionReason, message string) status.Condition {
+	cond := status.Condition{
+		Type:    condType,
+		Status:  corev1.ConditionFalse,
+		Reason:  reason,
+		Message: message,
+	}
+	if value {
+		cond.Status = corev1.ConditionTrue
+	}
+	return cond
+}
+
+func orUnknown(value string) string {
+	if len(value) == 0 {
+		return "Unknown"
+	}
+	return value
+}
@@ -3,15 +3,19 @@ package application
 import (
 	"context"
 	"fmt"
-	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
 	"github.com/go-logr/logr"
 	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
-	"github.com/mdvorak/argo-application-operator/pkg/argocd"
+	"github.com/mdvorak/argo-application-operator/pkg/notifier"
+	"github.com/mdvorak/argo-application-operator/pkg/webhook"
 	"github.com/operator-framework/operator-sdk/pkg/status"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -19,41 +23,73 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"time"
 )
 
 var log = logf.Log.WithName("controller_application")
-var destinationServer string
-var argoNamespace string
 
 const applicationFinalizer = "finalizer.application.ops.csas.cz"
-const availableCondition = "Available"
 
 const ownerApiGroupLabel = "application.ops.csas.cz/owner-api-group"
 const ownerApiVersionLabel = "application.ops.csas.cz/owner-api-version"
 const ownerKindLabel = "application.ops.csas.cz/owner-kind"
 const ownerNameLabel = "application.ops.csas.cz/owner-name"
 const ownerNamespaceLabel = "application.ops.csas.cz/owner-namespace"
+const ownerClusterLabel = "application.ops.csas.cz/owner-cluster"
 const managedByLabel = "app.kubernetes.io/managed-by"
+const sourceGenerationAnnotation = "application.ops.csas.cz/source-generation"
+const sourceUIDAnnotation = "application.ops.csas.cz/source-uid"
+
+// How often status.lastReconciledAt is refreshed, when status does not change
+const lastReconciledAtRefreshInterval = 5 * time.Minute
 
 // Add creates a new Application Controller and adds it to the Manager. The Manager will set fields on the Controller
 // and Start it when the Manager is Started.
 func Add(mgr manager.Manager) error {
-	return add(mgr, newReconciler(mgr))
+	b, err := newBackend(mgr)
+	if err != nil {
+		return err
+	}
+	n, err := notifier.NewFromEnv()
+	if err != nil {
+		return fmt.Errorf("failed to create status notifier: %w", err)
+	}
+	if n != nil {
+		if err := mgr.Add(n); err != nil {
+			return fmt.Errorf("failed to add status notifier: %w", err)
+		}
+	}
+	return add(mgr, newReconciler(mgr, b, n), b)
 }
 
 // newReconciler returns a new reconcile.Reconciler
-func newReconciler(mgr manager.Manager) reconcile.Reconciler {
-	return &ReconcileApplication{client: mgr.GetClient(), scheme: mgr.GetScheme()}
+func newReconciler(mgr manager.Manager, b backend, n *notifier.Notifier) reconcile.Reconciler {
+	return &ReconcileApplication{
+		client:   mgr.GetClient(),
+		reader:   mgr.GetAPIReader(),
+		scheme:   mgr.GetScheme(),
+		recorder: mgr.GetEventRecorderFor("application-controller"),
+		backend:  b,
+		notifier: n,
+	}
 }
 
 // add adds a new Controller to mgr with r as the reconcile.Reconciler
-func add(mgr manager.Manager, r reconcile.Reconciler) error {
+func add(mgr manager.Manager, r reconcile.Reconciler, b backend) error {
 	var err error
 
-	destinationServer = argocd.GetDestinationServer()
-	argoNamespace, err = argocd.GetNamespace()
+	expirationWarning, err = getExpirationWarning()
+	if err != nil {
+		return err
+	}
+	propagatedLabelPrefixes = getList(PropagatedLabelPrefixesEnvVar)
+	propagatedAnnotationPrefixes = getList(PropagatedAnnotationPrefixesEnvVar)
+	propagatedNamespaceLabels = getList(PropagatedNamespaceLabelsEnvVar)
+	notificationTriggers = getList(NotificationTriggersEnvVar)
+	notificationServices = getList(NotificationServicesEnvVar)
+	webhookAnnotationsTrusted, err = webhook.GetEnabled()
 	if err != nil {
-		return fmt.Errorf("argo namespace must be set: %w", err)
+		return err
 	}
 
 	// Create a new controller
@@ -68,15 +104,57 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 		return fmt.Errorf("failed to watch source objects: %w", err)
 	}
 
-	// Watch for changes to secondary resource Application and requeue the owner Application
-	err = c.Watch(&source.Kind{Type: &argocdv1alpha1.Application{}}, &handler.EnqueueRequestsFromMapFunc{
-		ToRequests: handler.ToRequestsFunc(watchMapFunc),
-	}, argocd.ApplicationUpdatedPredicate{})
+	// Watch for changes of dependencies and requeue their dependents
+	err = mgr.GetFieldIndexer().IndexField(&opsv1alpha1.Application{}, dependsOnField, dependsOnIndexer)
 	if err != nil {
-		return fmt.Errorf("failed to watch target objects: %w", err)
+		return fmt.Errorf("failed to index %s field: %w", dependsOnField, err)
+	}
+	err = c.Watch(&source.Kind{Type: &opsv1alpha1.Application{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: &dependentsMapper{client: mgr.GetClient()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch dependencies of source objects: %w", err)
 	}
 
-	return nil
+	// Watch for changes of change freezes and requeue all Application objects
+	err = c.Watch(&source.Kind{Type: &opsv1alpha1.ChangeFreeze{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: &allApplicationsMapper{client: mgr.GetClient()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch change freezes: %w", err)
+	}
+
+	// Watch for changes of tenants and requeue all Application objects
+	err = c.Watch(&source.Kind{Type: &opsv1alpha1.Tenant{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: &allApplicationsMapper{client: mgr.GetClient()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch tenants: %w", err)
+	}
+
+	// Watch for changes of namespaces and requeue applications in them, or deploying into them
+	err = mgr.GetFieldIndexer().IndexField(&opsv1alpha1.Application{}, destinationNamespaceField, destinationNamespaceIndexer)
+	if err != nil {
+		return fmt.Errorf("failed to index %s field: %w", destinationNamespaceField, err)
+	}
+	err = c.Watch(&source.Kind{Type: &corev1.Namespace{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: &namespaceApplicationsMapper{client: mgr.GetClient()},
+	}, namespaceMetadataChangedPredicate)
+	if err != nil {
+		return fmt.Errorf("failed to watch namespaces: %w", err)
+	}
+
+	// Index repository URLs for push webhook receiver
+	err = mgr.GetFieldIndexer().IndexField(&opsv1alpha1.Application{}, repoURLField, repoURLIndexer)
+	if err != nil {
+		return fmt.Errorf("failed to index %s field: %w", repoURLField, err)
+	}
+	if err := addReceiver(mgr, b); err != nil {
+		return fmt.Errorf("failed to add webhook receiver: %w", err)
+	}
+
+	// Watch for changes to target objects and requeue the owner Application
+	return b.watch(c)
 }
 
 // Filtering function for generic watcher
@@ -109,13 +187,20 @@ type ReconcileApplication struct {
 	// This client, initialized using mgr.Client() above, is a split client
 	// that reads objects from the cache and writes to the apiserver
 	client client.Client
-	scheme *runtime.Scheme
+	// Reader of the apiserver, bypassing the cache, for objects which must be up to date
+	reader   client.Reader
+	scheme   *runtime.Scheme
+	recorder record.EventRecorder
+	backend  backend
+	// Sends status transitions to an external webhook, nil when disabled
+	notifier *notifier.Notifier
 }
 
 // Reconcile reads that state of the cluster for a Application object and makes changes based on the state read
 // and what is in the Application.Spec
 //
-// Manages Application.argocd.io object with corresponding specification in namespace set by ARGO_NAMESPACE env var.
+// Manages target objects with corresponding specification using configured delivery backend, by default
+// Application.argocd.io object in namespace set by ARGOCD_NAMESPACE env var.
 //
 // Note:
 // The Controller will requeue the Request to be processed again if the returned error is non-nil or
@@ -140,28 +225,36 @@ func (r *ReconcileApplication) Reconcile(request reconcile.Request) (reconcile.R
 		return reconcile.Result{}, err
 	}
 
-	// Reconciliation logic
+	// Reconciliation logic, computing desired status in-memory
+	original := instance.DeepCopy()
 	result, available, err := r.reconcileApplication(ctx, reqLogger, instance)
 
 	// Update status
-	r.updateCondition(ctx, reqLogger, instance, r.newAvailableCondition(available, err))
+	r.setCondition(reqLogger, instance, r.newAvailableCondition(available, err))
+	summaryConditions := newSummaryConditions(instance, available, err)
+	for _, cond := range summaryConditions {
+		r.setCondition(reqLogger, instance, cond)
+	}
+	instance.Status.Summary = newSummary(summaryConditions)
+	instance.Status.ObservedGeneration = instance.Generation
+	r.updateStatus(ctx, reqLogger, original, instance)
 
-	// Return
+	// Return, errors which won't be resolved by retrying are reported in status only
+	if err != nil {
+		reqLogger.Info("reconcile failed", "Reason", errorReason(err), "Error", err.Error())
+	}
+	result, err = resultForError(result, err)
 	reqLogger.Info("reconcile finished")
 	return result, err
 }
 
 func (r *ReconcileApplication) reconcileApplication(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application) (reconcile.Result, bool, error) {
-	// Define a new Argo Application object
-	app := newApplication(cr)
-	appLogger := logger.WithValues("Application.Namespace", app.Namespace, "Application.Name", app.Name)
-
 	// Check if the instance is marked to be deleted, which is indicated by the deletion timestamp being set.
 	markedToBeDeleted := cr.GetDeletionTimestamp() != nil
 	if markedToBeDeleted {
 		// Delete target object
-		appLogger.Info("Application.ops.csas.cz is marked to be deleted")
-		result, err := r.reconcileDeletion(ctx, appLogger, cr, app)
+		logger.Info("Application.ops.csas.cz is marked to be deleted")
+		result, err := r.reconcileDeletion(ctx, logger, cr)
 
 		return result, false, err
 	}
@@ -174,58 +267,73 @@ func (r *ReconcileApplication) reconcileApplication(ctx context.Context, logger
 		}
 	}
 
-	// Update application
-	result, err := r.reconcileUpdate(ctx, appLogger, cr, app)
-	return result, true, err
-}
+	// Delete expired application, schedule the expiration otherwise
+	expirationResult, expired, err := r.reconcileExpiration(ctx, logger, cr)
+	if err != nil || expired {
+		return reconcile.Result{}, false, err
+	}
+
+	opts := targetOptions{}
 
-func (r *ReconcileApplication) reconcileUpdate(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application, app *argocdv1alpha1.Application) (reconcile.Result, error) {
-	// Check if this Application already exists
-	found := &argocdv1alpha1.Application{}
-	err := r.client.Get(ctx, types.NamespacedName{Name: app.Name, Namespace: app.Namespace}, found)
-	if err != nil && k8serrors.IsNotFound(err) {
-		logger.Info("creating a new Application.argocd.io")
-		err = r.client.Create(ctx, app)
+	// Hold automated sync until all dependencies are synced and healthy
+	if len(cr.Spec.DependsOn) > 0 {
+		cond, err := r.checkDependencies(ctx, cr)
 		if err != nil {
-			return reconcile.Result{}, fmt.Errorf("failed to create Application.argocd.io: %w", err)
+			return reconcile.Result{}, false, err
 		}
 
-		// Add reference
-		r.addReference(ctx, logger, cr, app)
+		r.setCondition(logger, cr, cond)
+		opts.disableAutomatedSync = opts.disableAutomatedSync || cond.IsTrue()
+	} else {
+		r.removeCondition(logger, cr, waitingForDependenciesCondition)
+	}
 
-		// Application created successfully - don't requeue
-		return reconcile.Result{}, nil
-	} else if err != nil {
-		return reconcile.Result{}, fmt.Errorf("failed to get existing Application.argocd.io: %w", err)
+	// Disable automated sync during change freeze
+	freezeResult, frozen, err := r.reconcileChangeFreeze(ctx, logger, cr)
+	if err != nil {
+		return reconcile.Result{}, false, err
 	}
+	opts.disableAutomatedSync = opts.disableAutomatedSync || frozen
 
-	// Verify ownership
-	if isApplicationOwnedBy(found, cr) {
-		// Not owned by this CR! This will fail repeatedly, but its ok - should not happen in real-life
-		return reconcile.Result{}, fmt.Errorf("object %s.%s \"%s\" in namespace \"%s\" already exists, and it is not owned by this object", found.Kind, found.GroupVersionKind().Group, found.Name, found.Namespace)
+	// Verify destination namespace belongs to the same tenant
+	opts.tenantNamespaces, err = r.checkDestination(ctx, cr)
+	if err != nil {
+		return reconcile.Result{}, false, err
 	}
 
-	// Add reference
-	r.addReference(ctx, logger, cr, found)
+	// Propagate labels and annotations
+	opts.labels, opts.annotations, err = r.propagatedMetadata(ctx, cr)
+	if err != nil {
+		return reconcile.Result{}, false, err
+	}
 
-	// Application exists, update
-	if patchApplication(found, app) {
-		logger.Info("updating existing Application.argocd.io")
-		err = r.client.Update(ctx, found)
-		if err != nil {
-			return reconcile.Result{}, fmt.Errorf("failed to update existing Application.argocd.io: %w", err)
-		}
+	// Render notification subscriptions, they are applied the same way as propagated annotations
+	subscriptions, err := notificationAnnotations(cr)
+	if err != nil {
+		return reconcile.Result{}, false, err
+	}
+	for annotation, value := range subscriptions {
+		opts.annotations[annotation] = value
 	}
 
-	// Application already exists - don't requeue
-	return reconcile.Result{}, nil
+	opts.driftPolicy, err = r.driftPolicy(ctx, cr.Namespace)
+	if err != nil {
+		return reconcile.Result{}, false, err
+	}
+
+	// Update target objects
+	result, state, err := r.backend.reconcile(ctx, logger, cr, opts)
+	r.updateTargetState(logger, cr, state, err == nil)
+
+	return sooner(sooner(result, expirationResult), freezeResult), true, err
 }
 
-func (r *ReconcileApplication) reconcileDeletion(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application, app *argocdv1alpha1.Application) (reconcile.Result, error) {
+func (r *ReconcileApplication) reconcileDeletion(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application) (reconcile.Result, error) {
 	if contains(cr.GetFinalizers(), applicationFinalizer) {
 		// Run finalization logic for our finalizer. If the finalization logic fails,
 		// don't remove the finalizer so that we can retry during the next reconciliation.
-		if err := r.finalizeApplication(ctx, logger, app); err != nil {
+		logger.Info("running finalizer " + applicationFinalizer)
+		if err := r.backend.finalize(ctx, logger, cr); err != nil {
 			return reconcile.Result{}, fmt.Errorf("failed to finalize Application.ops.csas.cz: %w", err)
 		}
 
@@ -239,47 +347,20 @@ func (r *ReconcileApplication) reconcileDeletion(ctx context.Context, logger log
 	return reconcile.Result{}, nil
 }
 
-func (r *ReconcileApplication) finalizeApplication(ctx context.Context, logger logr.Logger, app *argocdv1alpha1.Application) error {
-	logger.Info("running finalizer " + applicationFinalizer)
-
-	// Check if this Application exists
-	found := &argocdv1alpha1.Application{}
-	err := r.client.Get(ctx, types.NamespacedName{Name: app.Name, Namespace: app.Namespace}, found)
-	if err != nil {
-		// If there was error but it wasn't NotFound, propagate the error
-		if k8serrors.IsNotFound(err) {
-			// Already deleted, nothing to do
-			logger.Info("Application.argocd.io already deleted")
-			return nil
-		}
-
-		return fmt.Errorf("failed to get Application.argocd.io for deletion: %w", err)
-	}
-
-	// Delete
-	logger.Info("deleting Application.argocd.io")
-	err = r.client.Delete(ctx, found)
-	if err != nil {
-		return fmt.Errorf("failed to delete Application.argocd.io: %w", err)
-	}
-
-	return nil
-}
-
 // Create new Condition of type Available with human readable message
 func (r *ReconcileApplication) newAvailableCondition(available bool, err error) status.Condition {
 	if err != nil {
 		// Error
 		return status.Condition{
-			Type:    availableCondition,
+			Type:    opsv1alpha1.AvailableCondition,
 			Status:  corev1.ConditionFalse,
-			Reason:  "Failed",
+			Reason:  errorReason(err),
 			Message: err.Error(),
 		}
 	} else if available {
 		// Exists
 		return status.Condition{
-			Type:    availableCondition,
+			Type:    opsv1alpha1.AvailableCondition,
 			Status:  corev1.ConditionTrue,
 			Reason:  "Created",
 			Message: "reconciliation successful",
@@ -287,58 +368,111 @@ func (r *ReconcileApplication) newAvailableCondition(available bool, err error)
 	} else {
 		// Deleted
 		return status.Condition{
-			Type:    availableCondition,
+			Type:    opsv1alpha1.AvailableCondition,
 			Status:  corev1.ConditionFalse,
-			Reason:  "Deleted",
+			Reason:  opsv1alpha1.DeletedReason,
 			Message: "reconciliation successful",
 		}
 	}
 }
 
 // Store a Condition into CR status.conditions
-func (r *ReconcileApplication) updateCondition(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application, cond status.Condition) {
-	// Copy instance for comparison
-	newInstance := cr.DeepCopy()
-
-	// Update only if changed
-	if newInstance.Status.Conditions.SetCondition(cond) {
+func (r *ReconcileApplication) setCondition(logger logr.Logger, cr *opsv1alpha1.Application, cond status.Condition) {
+	if cr.Status.Conditions.SetCondition(cond, cr.Generation) {
 		logger.Info("updating condition", "Condition.Type", cond.Type, "Condition.Status", cond.Status, "Condition.Reason", cond.Reason, "Condition.Message", cond.Message)
+	}
+}
 
-		// Patch object
-		if err := r.client.Status().Patch(ctx, newInstance, client.MergeFrom(cr)); err != nil && !k8serrors.IsNotFound(err) {
-			// Log error without failing - note that NotFound is ignored silently
-			logger.Error(err, "failed to update status of Application.ops.csas.cz")
-		} else if err != nil {
-			// Update original instance
-			cr.Status = newInstance.Status
+// Remove a Condition from CR status.conditions
+func (r *ReconcileApplication) removeCondition(logger logr.Logger, cr *opsv1alpha1.Application, condType status.ConditionType) {
+	if cr.Status.Conditions.RemoveCondition(condType) {
+		logger.Info("removing condition", "Condition.Type", condType)
+	}
+}
+
+// Store observed state of target objects into CR status. When the state is complete, references are replaced,
+// so objects deleted by the backend are removed from them, otherwise they are only added.
+func (r *ReconcileApplication) updateTargetState(logger logr.Logger, cr *opsv1alpha1.Application, state targetState, complete bool) {
+	if complete {
+		if !equalReferences(cr.Status.References, state.references) {
+			logger.Info("replacing references", "References", len(state.references))
+			cr.Status.References = state.references
 		}
+	} else {
+		for _, ref := range state.references {
+			if cr.Status.References.SetReference(ref) {
+				logger.Info("updating reference", "Reference.Kind", ref.Kind, "Reference.Namespace", ref.Namespace, "Reference.Name", ref.Name)
+			}
+		}
+	}
+	if state.observed && (cr.Status.SyncStatus != state.syncStatus || cr.Status.HealthStatus != state.healthStatus || cr.Status.SyncedRevision != state.syncedRevision) {
+		logger.Info("updating target status", "SyncStatus", state.syncStatus, "HealthStatus", state.healthStatus, "SyncedRevision", state.syncedRevision)
+		cr.Status.SyncStatus = state.syncStatus
+		cr.Status.HealthStatus = state.healthStatus
+		cr.Status.SyncedRevision = state.syncedRevision
+	}
+	if state.paused != nil {
+		r.setCondition(logger, cr, *state.paused)
+	} else {
+		r.removeCondition(logger, cr, pausedCondition)
+	}
+	if state.conflict != nil {
+		r.setCondition(logger, cr, *state.conflict)
+	} else {
+		r.removeCondition(logger, cr, conflictCondition)
+	}
+	if len(state.drift) > 0 {
+		// Report only when the drift starts, not on every reconcile while it lasts
+		if cr.Status.Conditions.GetCondition(driftedCondition) == nil {
+			driftTotal.WithLabelValues(cr.Namespace, cr.Name).Inc()
+			r.recorder.Event(cr, corev1.EventTypeWarning, "Drifted", "target objects drifted from the last applied state: "+formatDrift(state.drift))
+		}
+		r.setCondition(logger, cr, newDriftedCondition(state.drift))
+	} else if complete {
+		r.removeCondition(logger, cr, driftedCondition)
+	}
+	if !equalSyncWindowStatus(cr.Status.SyncWindow, state.syncWindow) {
+		logger.Info("updating sync window status")
+		cr.Status.SyncWindow = state.syncWindow
 	}
 }
 
-// Store a Reference to given Application into CR status.references
-func (r *ReconcileApplication) addReference(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application, app *argocdv1alpha1.Application) {
-	// Copy instance for comparison
-	newInstance := cr.DeepCopy()
+// Persist status computed during reconciliation in a single write, retrying on conflict.
+// Nothing is written when the status has not changed.
+func (r *ReconcileApplication) updateStatus(ctx context.Context, logger logr.Logger, original, cr *opsv1alpha1.Application) {
+	// Unchanged status is written only to refresh lastReconciledAt, once in a while
+	now := metav1.Now()
+	if equality.Semantic.DeepEqual(original.Status, cr.Status) && cr.Status.LastReconciledAt != nil &&
+		now.Sub(cr.Status.LastReconciledAt.Time) < lastReconciledAtRefreshInterval {
+		return
+	}
 
-	// Convert to Reference
-	ref, err := opsv1alpha1.ReferenceFromApplication(app, r.scheme)
+	cr.Status.LastReconciledAt = &now
+	newStatus := cr.Status
+	conflicted := false
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if conflicted {
+			// Status is owned by the operator, so it replaces status of the latest version. It is read from the API
+			// server, cache might not have seen it yet.
+			if err := r.reader.Get(ctx, types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace}, cr); err != nil {
+				return err
+			}
+			cr.Status = newStatus
+		}
+		err := r.client.Status().Update(ctx, cr)
+		conflicted = k8serrors.IsConflict(err)
+		return err
+	})
 	if err != nil {
-		logger.Error(err, "failed build Reference from app object")
+		if !k8serrors.IsNotFound(err) {
+			// Log error without failing - note that NotFound is ignored silently
+			logger.Error(err, "failed to update status of Application.ops.csas.cz")
+		}
 		return
 	}
 
-	// Update only if changed
-	if newInstance.Status.References.SetReference(ref) {
-		logger.Info("updating reference")
-
-		// Patch object
-		if err := r.client.Status().Patch(ctx, newInstance, client.MergeFrom(cr)); err != nil {
-			// Log error without failing
-			logger.Error(err, "failed to add reference to Application.ops.csas.cz")
-		} else {
-			// Update original instance
-			cr.Status = newInstance.Status
-		}
+	if original.Status.SyncStatus != cr.Status.SyncStatus || original.Status.HealthStatus != cr.Status.HealthStatus {
+		r.notifyStatusChanged(original, cr)
 	}
 }
 
@@ -352,7 +486,20 @@ func (r *ReconcileApplication) updateFinalizers(ctx context.Context, cr *opsv1al
 		return err
 	}
 
-	// Propagate change to original instance
+	// Propagate change to original instance, including new version for the status update
 	cr.SetFinalizers(newFinalizers)
+	cr.SetResourceVersion(newInstance.GetResourceVersion())
 	return nil
 }
+
+func equalReferences(a opsv1alpha1.References, b []opsv1alpha1.Reference) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
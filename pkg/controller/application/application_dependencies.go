@@ -0,0 +1,152 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"github.com/operator-framework/operator-sdk/pkg/status"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
+)
+
+const waitingForDependenciesCondition = "WaitingForDependencies"
+
+// Name of the field index of Application.ops.csas.cz, containing spec.dependsOn values
+const dependsOnField = "spec.dependsOn"
+
+// Indexer function for dependsOnField
+func dependsOnIndexer(obj runtime.Object) []string {
+	return obj.(*opsv1alpha1.Application).Spec.DependsOn
+}
+
+// Maps Application.ops.csas.cz to all applications in the same namespace, that depend on it
+type dependentsMapper struct {
+	client client.Client
+}
+
+// Map implements handler.Mapper
+func (m *dependentsMapper) Map(obj handler.MapObject) []reconcile.Request {
+	list := &opsv1alpha1.ApplicationList{}
+	err := m.client.List(context.TODO(), list, client.InNamespace(obj.Meta.GetNamespace()), client.MatchingFields{dependsOnField: obj.Meta.GetName()})
+	if err != nil {
+		log.Error(err, "failed to list dependent Application.ops.csas.cz", "Namespace", obj.Meta.GetNamespace(), "Name", obj.Meta.GetName())
+		return []reconcile.Request{}
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, item := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: item.Name, Namespace: item.Namespace}})
+	}
+	return requests
+}
+
+// Returns true when the application is synced and healthy
+func isReady(cr *opsv1alpha1.Application) bool {
+	return cr.DeletionTimestamp == nil &&
+		cr.Status.SyncStatus == argocdv1alpha1.SyncStatusCodeSynced &&
+		cr.Status.HealthStatus == argocdv1alpha1.HealthStatusHealthy
+}
+
+// Evaluates dependencies of the CR and returns WaitingForDependencies condition
+func (r *ReconcileApplication) checkDependencies(ctx context.Context, cr *opsv1alpha1.Application) (status.Condition, error) {
+	// Cycles
+	if cycle, err := r.findDependencyCycle(ctx, cr.Namespace, []string{cr.Name}, map[string]bool{cr.Name: true}); err != nil {
+		return status.Condition{}, err
+	} else if cycle != nil {
+		return status.Condition{
+			Type:    waitingForDependenciesCondition,
+			Status:  corev1.ConditionTrue,
+			Reason:  "DependencyCycle",
+			Message: fmt.Sprintf("dependency cycle detected: %s", strings.Join(cycle, " -> ")),
+		}, nil
+	}
+
+	// Direct dependencies
+	var missing, notReady []string
+	for _, name := range cr.Spec.DependsOn {
+		dep := &opsv1alpha1.Application{}
+		err := r.client.Get(ctx, types.NamespacedName{Name: name, Namespace: cr.Namespace}, dep)
+		if err != nil && k8serrors.IsNotFound(err) {
+			missing = append(missing, name)
+		} else if err != nil {
+			return status.Condition{}, fmt.Errorf("failed to get dependency %s: %w", name, err)
+		} else if !isReady(dep) {
+			notReady = append(notReady, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return status.Condition{
+			Type:    waitingForDependenciesCondition,
+			Status:  corev1.ConditionTrue,
+			Reason:  "DependencyNotFound",
+			Message: fmt.Sprintf("dependencies not found: %s", strings.Join(missing, ", ")),
+		}, nil
+	}
+	if len(notReady) > 0 {
+		return status.Condition{
+			Type:    waitingForDependenciesCondition,
+			Status:  corev1.ConditionTrue,
+			Reason:  "DependencyNotReady",
+			Message: fmt.Sprintf("dependencies not synced and healthy: %s", strings.Join(notReady, ", ")),
+		}, nil
+	}
+
+	return status.Condition{
+		Type:    waitingForDependenciesCondition,
+		Status:  corev1.ConditionFalse,
+		Reason:  "DependenciesReady",
+		Message: "all dependencies are synced and healthy",
+	}, nil
+}
+
+// Walks dependencies of the last application in the path, and returns a path which ends where it started,
+// or nil if there is no such cycle. Every application is visited at most once, since only reachability of the start
+// matters.
+func (r *ReconcileApplication) findDependencyCycle(ctx context.Context, namespace string, path []string, visited map[string]bool) ([]string, error) {
+	dep := &opsv1alpha1.Application{}
+	err := r.client.Get(ctx, types.NamespacedName{Name: path[len(path)-1], Namespace: namespace}, dep)
+	if err != nil && k8serrors.IsNotFound(err) {
+		// Missing dependencies are reported elsewhere
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get dependency %s: %w", path[len(path)-1], err)
+	}
+
+	for _, name := range dep.Spec.DependsOn {
+		if name == path[0] {
+			// Back at the start
+			return append(path, name), nil
+		}
+		if visited[name] {
+			// Already walked, or cycle which does not contain the start, it is going to be reported by its members
+			continue
+		}
+		visited[name] = true
+
+		cycle, err := r.findDependencyCycle(ctx, namespace, append(path[:len(path):len(path)], name), visited)
+		if err != nil || cycle != nil {
+			return cycle, err
+		}
+	}
+
+	return nil, nil
+}
+
+// Returns copy of the sync policy without automated sync
+func withoutAutomatedSync(policy *argocdv1alpha1.SyncPolicy) *argocdv1alpha1.SyncPolicy {
+	if policy == nil || policy.Automated == nil {
+		return policy
+	}
+
+	policy = policy.DeepCopy()
+	policy.Automated = nil
+	return policy
+}
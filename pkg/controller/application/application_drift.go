@@ -0,0 +1,213 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/operator-framework/operator-sdk/pkg/status"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Namespace annotation, which sets how drift of generated applications from the desired state is handled
+const driftPolicyAnnotation = "application.ops.csas.cz/drift-policy"
+
+// Drift policies
+const (
+	// Target is applied without forcing ownership, fields modified by others are reported by Conflict condition
+	driftPolicyConflict = "conflict"
+	// Drifted fields are reverted, by forcing their ownership
+	driftPolicyRevert = "revert"
+	// Target is not updated while it drifts, e.g. during migration
+	driftPolicyReport = "report"
+)
+
+// Annotation of the target object, holding JSON of the configuration last applied by the operator
+const lastAppliedAnnotation = "application.ops.csas.cz/last-applied"
+
+const driftedCondition = "Drifted"
+
+// Maximum number of drifted fields listed in the event
+const maxDriftEventFields = 10
+
+// Returns drift policy of the namespace, by default conflict
+func (r *ReconcileApplication) driftPolicy(ctx context.Context, namespace string) (string, error) {
+	ns := &corev1.Namespace{}
+	if err := r.client.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return "", fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+	}
+
+	switch policy := ns.Annotations[driftPolicyAnnotation]; policy {
+	case "":
+		return driftPolicyConflict, nil
+	case driftPolicyConflict, driftPolicyRevert, driftPolicyReport:
+		return policy, nil
+	default:
+		return "", newPolicyViolationError("namespace %s has invalid %s annotation %q, must be one of %s, %s or %s", namespace, driftPolicyAnnotation, policy, driftPolicyConflict, driftPolicyRevert, driftPolicyReport)
+	}
+}
+
+// Records the configuration into lastAppliedAnnotation of itself, so drift can be detected on next reconcile
+func setLastApplied(applied *unstructured.Unstructured) error {
+	value, err := json.Marshal(applied.Object)
+	if err != nil {
+		return fmt.Errorf("failed to serialize applied configuration: %w", err)
+	}
+
+	annotations := applied.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[lastAppliedAnnotation] = string(value)
+	applied.SetAnnotations(annotations)
+	return nil
+}
+
+// Returns fields of the live object, which differ from the configuration last applied by the operator.
+// Nothing is reported for objects, which have not been applied with the annotation yet.
+func detectDrift(live *unstructured.Unstructured) ([]string, error) {
+	value, ok := live.GetAnnotations()[lastAppliedAnnotation]
+	if !ok {
+		return nil, nil
+	}
+
+	lastApplied := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(value), &lastApplied); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", lastAppliedAnnotation, err)
+	}
+
+	// Round trip the live object, so numbers are of the same type as in the annotation
+	raw, err := json.Marshal(live.Object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize live object: %w", err)
+	}
+	liveObject := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &liveObject); err != nil {
+		return nil, fmt.Errorf("failed to deserialize live object: %w", err)
+	}
+
+	return diffObject(lastApplied, liveObject), nil
+}
+
+// Returns the configuration to apply, when the target drifted and the drift should be kept. Fields drifted in the live
+// object, which the configuration has not changed since the last apply, keep their live values, so that only changes
+// of the CR are applied. Returns nil when nothing has changed since the last apply.
+func keepDrift(applied, live *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	value := live.GetAnnotations()[lastAppliedAnnotation]
+	if value == applied.GetAnnotations()[lastAppliedAnnotation] {
+		return nil, nil
+	}
+
+	lastApplied := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(value), &lastApplied); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation: %w", lastAppliedAnnotation, err)
+	}
+
+	// Round trip both objects, so numbers are of the same type as in the annotation
+	desired := map[string]interface{}{}
+	liveObject := map[string]interface{}{}
+	for _, obj := range []struct {
+		from map[string]interface{}
+		to   *map[string]interface{}
+	}{{applied.Object, &desired}, {live.Object, &liveObject}} {
+		raw, err := json.Marshal(obj.from)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize object: %w", err)
+		}
+		if err := json.Unmarshal(raw, obj.to); err != nil {
+			return nil, fmt.Errorf("failed to deserialize object: %w", err)
+		}
+	}
+
+	keepDriftedFields(desired, lastApplied, liveObject)
+	return &unstructured.Unstructured{Object: desired}, nil
+}
+
+func keepDriftedFields(desired, lastApplied, live map[string]interface{}) {
+	for key, lastValue := range lastApplied {
+		desiredValue, desiredFound := desired[key]
+		liveValue, liveFound := live[key]
+
+		// Compare nested objects field by field
+		lastMap, ok := lastValue.(map[string]interface{})
+		desiredMap, desiredOk := desiredValue.(map[string]interface{})
+		liveMap, liveOk := liveValue.(map[string]interface{})
+		if ok && desiredOk && liveOk {
+			keepDriftedFields(desiredMap, lastMap, liveMap)
+			continue
+		}
+
+		// Changed by the CR, or not drifted
+		if !desiredFound || !reflect.DeepEqual(lastValue, desiredValue) || (liveFound && reflect.DeepEqual(lastValue, liveValue)) {
+			continue
+		}
+		if liveFound {
+			desired[key] = liveValue
+		} else {
+			delete(desired, key)
+		}
+	}
+}
+
+func newDriftedCondition(drift []string) status.Condition {
+	return status.Condition{
+		Type:    driftedCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  "TargetModified",
+		Message: "target objects drifted from the last applied state: " + formatDrift(drift),
+	}
+}
+
+// Returns sorted list of fields of the desired object, which differ in the live object, formatted as
+// "path: desired -> live". Fields not set in the desired object are ignored, they are managed by others.
+func diffObject(desired, live map[string]interface{}) []string {
+	var drift []string
+	diffFields("", desired, live, &drift)
+	sort.Strings(drift)
+	return drift
+}
+
+func diffFields(path string, desired, live map[string]interface{}, drift *[]string) {
+	for key, desiredValue := range desired {
+		fieldPath := key
+		if path != "" {
+			fieldPath = path + "." + key
+		}
+		liveValue, found := live[key]
+
+		// Compare nested objects field by field
+		desiredMap, ok := desiredValue.(map[string]interface{})
+		liveMap, liveOk := liveValue.(map[string]interface{})
+		if ok && (liveOk || !found) {
+			diffFields(fieldPath, desiredMap, liveMap, drift)
+			continue
+		}
+
+		if !found || !reflect.DeepEqual(desiredValue, liveValue) {
+			*drift = append(*drift, fmt.Sprintf("%s: %s -> %s", fieldPath, formatValue(desiredValue, true), formatValue(liveValue, found)))
+		}
+	}
+}
+
+func formatValue(value interface{}, found bool) string {
+	if !found {
+		return "<unset>"
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(b)
+}
+
+// Formats drifted fields for the event message
+func formatDrift(drift []string) string {
+	if len(drift) > maxDriftEventFields {
+		return strings.Join(drift[:maxDriftEventFields], "; ") + fmt.Sprintf("; and %d more", len(drift)-maxDriftEventFields)
+	}
+	return strings.Join(drift, "; ")
+}
@@ -0,0 +1,47 @@
+package application
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"reflect"
+	"testing"
+)
+
+func TestKeepDriftAppliesOnlyChangesOfCR(t *testing.T) {
+	newObject := func(spec map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	}
+	lastApplied := newObject(map[string]interface{}{"revision": "v1", "path": "app", "replicas": int64(1)})
+	if err := setLastApplied(lastApplied); err != nil {
+		t.Fatal(err)
+	}
+
+	// Path and replicas drifted
+	live := lastApplied.DeepCopy()
+	live.Object["spec"] = map[string]interface{}{"revision": "v1", "path": "manual", "replicas": int64(3)}
+
+	// Unchanged CR is not applied
+	unchanged := newObject(map[string]interface{}{"revision": "v1", "path": "app", "replicas": int64(1)})
+	if err := setLastApplied(unchanged); err != nil {
+		t.Fatal(err)
+	}
+	if actual, err := keepDrift(unchanged, live); err != nil || actual != nil {
+		t.Errorf("expected nothing to apply, got %v, %v", actual, err)
+	}
+
+	// Changed revision is applied, drifted fields keep their live values, unless the CR changed them too
+	changed := newObject(map[string]interface{}{"revision": "v2", "path": "app", "replicas": int64(2)})
+	if err := setLastApplied(changed); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := keepDrift(changed, live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{"revision": "v2", "path": "manual", "replicas": float64(2)}
+	if !reflect.DeepEqual(actual.Object["spec"], expected) {
+		t.Errorf("expected spec %v, got %v", expected, actual.Object["spec"])
+	}
+	if actual.GetAnnotations()[lastAppliedAnnotation] != changed.GetAnnotations()[lastAppliedAnnotation] {
+		t.Errorf("expected last applied configuration of the CR, got %s", actual.GetAnnotations()[lastAppliedAnnotation])
+	}
+}
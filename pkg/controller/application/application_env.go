@@ -0,0 +1,47 @@
+package application
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+//noinspection GoUnusedConst
+const (
+	ExpirationWarningEnvVar  = "APPLICATION_EXPIRATION_WARNING"
+	ExpirationWarningDefault = time.Hour
+	// Comma separated prefixes of CR labels and annotations, which are propagated to generated applications
+	PropagatedLabelPrefixesEnvVar      = "APPLICATION_PROPAGATED_LABEL_PREFIXES"
+	PropagatedAnnotationPrefixesEnvVar = "APPLICATION_PROPAGATED_ANNOTATION_PREFIXES"
+	// Comma separated namespace labels, which are propagated to generated applications
+	PropagatedNamespaceLabelsEnvVar = "APPLICATION_PROPAGATED_NAMESPACE_LABELS"
+	// Comma separated argocd-notifications triggers and services, which applications may subscribe to
+	NotificationTriggersEnvVar = "APPLICATION_NOTIFICATION_TRIGGERS"
+	NotificationServicesEnvVar = "APPLICATION_NOTIFICATION_SERVICES"
+)
+
+// Returns how long before expiration of an Application.ops.csas.cz a warning event is emitted
+func getExpirationWarning() (time.Duration, error) {
+	if value, ok := os.LookupEnv(ExpirationWarningEnvVar); ok && len(value) > 0 {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not a valid duration: %w", ExpirationWarningEnvVar, err)
+		}
+		return d, nil
+	} else {
+		// Default
+		return ExpirationWarningDefault, nil
+	}
+}
+
+// Returns comma separated list from given env var, empty when it is not set
+func getList(envVar string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(envVar), ",") {
+		if item = strings.TrimSpace(item); len(item) > 0 {
+			list = append(list, item)
+		}
+	}
+	return list
+}
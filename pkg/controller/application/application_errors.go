@@ -0,0 +1,108 @@
+package application
+
+import (
+	"errors"
+	"fmt"
+	"github.com/operator-framework/operator-sdk/pkg/status"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"net"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"time"
+)
+
+// Reasons of reconciliation errors, used in conditions
+const (
+	// Target object of the same name exists, and it is not owned by the CR
+	reasonNameConflict status.ConditionReason = "NameConflict"
+	// AppProject of the application does not exist
+	reasonProjectMissing status.ConditionReason = "ProjectMissing"
+	// Spec is valid, but it is not allowed by cluster policy, e.g. tenant or allowed notification triggers
+	reasonPolicyViolation status.ConditionReason = "PolicyViolation"
+	// Argo CD, or its API, is not reachable or not installed
+	reasonArgoUnavailable status.ConditionReason = "ArgoUnavailable"
+	// Spec of the CR is not valid
+	reasonInvalidSpec status.ConditionReason = "InvalidSpec"
+	// Operator is not allowed to manage target objects
+	reasonForbidden status.ConditionReason = "Forbidden"
+	// Any other error
+	reasonFailed status.ConditionReason = "Failed"
+)
+
+// How long to wait before retrying errors, which are not resolved by a change of watched objects
+const (
+	nameConflictRetryInterval   = 5 * time.Minute
+	projectMissingRetryInterval = time.Minute
+	forbiddenRetryInterval      = 5 * time.Minute
+)
+
+// Reconciliation error with a reason
+type reconcileError struct {
+	reason status.ConditionReason
+	err    error
+}
+
+func (e *reconcileError) Error() string {
+	return e.err.Error()
+}
+
+func (e *reconcileError) Unwrap() error {
+	return e.err
+}
+
+func newNameConflictError(format string, args ...interface{}) error {
+	return &reconcileError{reason: reasonNameConflict, err: fmt.Errorf(format, args...)}
+}
+
+func newProjectMissingError(format string, args ...interface{}) error {
+	return &reconcileError{reason: reasonProjectMissing, err: fmt.Errorf(format, args...)}
+}
+
+func newPolicyViolationError(format string, args ...interface{}) error {
+	return &reconcileError{reason: reasonPolicyViolation, err: fmt.Errorf(format, args...)}
+}
+
+func newInvalidSpecError(format string, args ...interface{}) error {
+	return &reconcileError{reason: reasonInvalidSpec, err: fmt.Errorf(format, args...)}
+}
+
+// Returns reason of the error. Errors of the API server are classified as well, when they are not typed.
+func errorReason(err error) status.ConditionReason {
+	var typed *reconcileError
+	if errors.As(err, &typed) {
+		return typed.reason
+	}
+
+	var netErr net.Error
+	switch {
+	case k8serrors.IsForbidden(err):
+		return reasonForbidden
+	case meta.IsNoMatchError(err), k8serrors.IsServiceUnavailable(err), k8serrors.IsTimeout(err), errors.As(err, &netErr):
+		return reasonArgoUnavailable
+	default:
+		return reasonFailed
+	}
+}
+
+// Returns result and error of the reconciliation, based on the error reason. Errors which won't be resolved by
+// retrying are not returned, so they are not retried with the default backoff.
+func resultForError(result reconcile.Result, err error) (reconcile.Result, error) {
+	if err == nil {
+		return result, nil
+	}
+
+	switch errorReason(err) {
+	case reasonInvalidSpec, reasonPolicyViolation:
+		// Resolved by change of the CR, its namespaces or tenants, which are watched, or by operator restart
+		return result, nil
+	case reasonNameConflict:
+		return sooner(result, reconcile.Result{RequeueAfter: nameConflictRetryInterval}), nil
+	case reasonProjectMissing:
+		return sooner(result, reconcile.Result{RequeueAfter: projectMissingRetryInterval}), nil
+	case reasonForbidden:
+		return sooner(result, reconcile.Result{RequeueAfter: forbiddenRetryInterval}), nil
+	default:
+		// Transient errors, retry with backoff
+		return result, err
+	}
+}
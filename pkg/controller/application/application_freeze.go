@@ -0,0 +1,109 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-logr/logr"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"github.com/operator-framework/operator-sdk/pkg/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strings"
+	"time"
+)
+
+const frozenCondition = "Frozen"
+
+// Maps any object to all Application.ops.csas.cz in the cluster
+type allApplicationsMapper struct {
+	client client.Client
+}
+
+// Map implements handler.Mapper
+func (m *allApplicationsMapper) Map(handler.MapObject) []reconcile.Request {
+	list := &opsv1alpha1.ApplicationList{}
+	if err := m.client.List(context.TODO(), list); err != nil {
+		log.Error(err, "failed to list Application.ops.csas.cz")
+		return []reconcile.Request{}
+	}
+
+	requests := make([]reconcile.Request, 0, len(list.Items))
+	for _, item := range list.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: item.Name, Namespace: item.Namespace}})
+	}
+	return requests
+}
+
+// Evaluates ChangeFreeze objects and returns Frozen condition, or nil when there is no active freeze.
+// Also returns the time when the soonest of the active freezes ends.
+func (r *ReconcileApplication) checkChangeFreeze(ctx context.Context) (*status.Condition, *time.Time, error) {
+	list := &opsv1alpha1.ChangeFreezeList{}
+	if err := r.client.List(ctx, list); err != nil {
+		return nil, nil, fmt.Errorf("failed to list ChangeFreeze.ops.csas.cz: %w", err)
+	}
+
+	now := metav1.Now()
+	var reasons []string
+	var until *time.Time
+
+	for _, freeze := range list.Items {
+		if !freeze.IsActive(now) {
+			continue
+		}
+
+		reason := freeze.Name
+		if len(freeze.Spec.Reason) > 0 {
+			reason += ": " + freeze.Spec.Reason
+		}
+		reasons = append(reasons, reason)
+
+		if freeze.Spec.Until != nil && (until == nil || freeze.Spec.Until.Time.Before(*until)) {
+			t := freeze.Spec.Until.Time
+			until = &t
+		}
+	}
+
+	if len(reasons) == 0 {
+		return nil, nil, nil
+	}
+
+	return &status.Condition{
+		Type:    frozenCondition,
+		Status:  corev1.ConditionTrue,
+		Reason:  "ChangeFreeze",
+		Message: fmt.Sprintf("automated sync is disabled by change freeze %s", strings.Join(reasons, ", ")),
+	}, until, nil
+}
+
+// Returns true when automated sync of the application must be disabled due to change freeze.
+// Schedules next reconcile when the freeze ends.
+func (r *ReconcileApplication) reconcileChangeFreeze(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application) (reconcile.Result, bool, error) {
+	// Not affected
+	if cr.Spec.SyncPolicy == nil || cr.Spec.SyncPolicy.Automated == nil {
+		r.removeCondition(logger, cr, frozenCondition)
+		return reconcile.Result{}, false, nil
+	}
+
+	cond, until, err := r.checkChangeFreeze(ctx)
+	if err != nil {
+		return reconcile.Result{}, false, err
+	}
+
+	// Not frozen, policy of the CR is used as is
+	if cond == nil {
+		r.removeCondition(logger, cr, frozenCondition)
+		return reconcile.Result{}, false, nil
+	}
+
+	// Frozen
+	r.setCondition(logger, cr, *cond)
+
+	if until != nil {
+		return reconcile.Result{RequeueAfter: time.Until(*until) + time.Second}, true, nil
+	}
+	return reconcile.Result{}, true, nil
+}
@@ -0,0 +1,50 @@
+package application
+
+import (
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"strings"
+)
+
+// Prefix of argocd-notifications subscription annotations, followed by trigger and service names
+const notificationSubscribeAnnotationPrefix = "notifications.argoproj.io/subscribe."
+
+// Allowed triggers and services, read from env vars
+var notificationTriggers []string
+var notificationServices []string
+
+// Returns argocd-notifications subscription annotations of the CR, after verifying its triggers and services
+// are allowed
+func notificationAnnotations(cr *opsv1alpha1.Application) (map[string]string, error) {
+	annotations := map[string]string{}
+
+	for _, subscription := range cr.Spec.Notifications {
+		if !contains(notificationTriggers, subscription.Trigger) {
+			return nil, newPolicyViolationError("notification trigger %s is not allowed", subscription.Trigger)
+		}
+		if !contains(notificationServices, subscription.Service) {
+			return nil, newPolicyViolationError("notification service %s is not allowed", subscription.Service)
+		}
+
+		// Same trigger and service might be listed more than once
+		annotation := notificationSubscribeAnnotationPrefix + subscription.Trigger + "." + subscription.Service
+		recipients := splitRecipients(annotations[annotation])
+		for _, recipient := range subscription.Recipients {
+			if strings.Contains(recipient, ";") {
+				return nil, newInvalidSpecError("notification recipient %s must not contain ';'", recipient)
+			}
+			if len(recipient) > 0 && !contains(recipients, recipient) {
+				recipients = append(recipients, recipient)
+			}
+		}
+		annotations[annotation] = strings.Join(recipients, ";")
+	}
+
+	return annotations, nil
+}
+
+func splitRecipients(value string) []string {
+	if len(value) == 0 {
+		return nil
+	}
+	return strings.Split(value, ";")
+}
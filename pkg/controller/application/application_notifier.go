@@ -0,0 +1,41 @@
+package application
+
+import (
+	"fmt"
+	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"github.com/mdvorak/argo-application-operator/pkg/notifier"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Type of the event sent when sync or health status of the application changes
+const statusChangedEventType = "cz.csas.ops.application.status.changed"
+
+// Data of the status changed event
+type statusChangedData struct {
+	Namespace            string                          `json:"namespace"`
+	Name                 string                          `json:"name"`
+	UID                  types.UID                       `json:"uid"`
+	SyncStatus           argocdv1alpha1.SyncStatusCode   `json:"syncStatus"`
+	HealthStatus         argocdv1alpha1.HealthStatusCode `json:"healthStatus"`
+	PreviousSyncStatus   argocdv1alpha1.SyncStatusCode   `json:"previousSyncStatus,omitempty"`
+	PreviousHealthStatus argocdv1alpha1.HealthStatusCode `json:"previousHealthStatus,omitempty"`
+}
+
+// Sends status transition of the application to the webhook, if configured
+func (r *ReconcileApplication) notifyStatusChanged(old, cr *opsv1alpha1.Application) {
+	if r.notifier == nil {
+		return
+	}
+
+	source := fmt.Sprintf("/apis/%s/namespaces/%s/applications/%s", opsv1alpha1.SchemeGroupVersion, cr.Namespace, cr.Name)
+	r.notifier.Send(notifier.NewEvent(statusChangedEventType, source, cr.Namespace+"/"+cr.Name, &statusChangedData{
+		Namespace:            cr.Namespace,
+		Name:                 cr.Name,
+		UID:                  cr.UID,
+		SyncStatus:           cr.Status.SyncStatus,
+		HealthStatus:         cr.Status.HealthStatus,
+		PreviousSyncStatus:   old.Status.SyncStatus,
+		PreviousHealthStatus: old.Status.HealthStatus,
+	}))
+}
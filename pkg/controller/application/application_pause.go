@@ -0,0 +1,50 @@
+package application
+
+import (
+	"fmt"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"github.com/operator-framework/operator-sdk/pkg/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const pausedCondition = "Paused"
+
+// Annotation which pauses updates of the target objects. It can be set either on Application.ops.csas.cz,
+// or by admins directly on the target object. Its value should identify who paused it.
+const pausedAnnotation = opsv1alpha1.PausedAnnotation
+
+// Returns Paused condition when updates of the target application are paused, nil otherwise.
+// Target might be nil, when it does not exist yet, kind of the target is used in messages, e.g. Application.argocd.io.
+func newPausedCondition(cr *opsv1alpha1.Application, target metav1.Object, kind string) *status.Condition {
+	if pausedBy, ok := cr.Annotations[pausedAnnotation]; ok {
+		// User recorded by the admission webhook is more reliable than free-form value
+		if recorded := cr.Annotations[opsv1alpha1.PausedByAnnotation]; webhookAnnotationsTrusted && len(recorded) > 0 {
+			pausedBy = recorded
+		}
+		return &status.Condition{
+			Type:    pausedCondition,
+			Status:  corev1.ConditionTrue,
+			Reason:  "PausedByAnnotation",
+			Message: fmt.Sprintf("updates of target objects are paused by %s", pausedByOrUnknown(pausedBy)),
+		}
+	}
+	if target != nil {
+		if pausedBy, ok := target.GetAnnotations()[pausedAnnotation]; ok {
+			return &status.Condition{
+				Type:    pausedCondition,
+				Status:  corev1.ConditionTrue,
+				Reason:  "TargetPausedByAnnotation",
+				Message: fmt.Sprintf("updates of %s \"%s\" are paused on the object itself by %s", kind, target.GetName(), pausedByOrUnknown(pausedBy)),
+			}
+		}
+	}
+	return nil
+}
+
+func pausedByOrUnknown(pausedBy string) string {
+	if len(pausedBy) == 0 {
+		return "unknown"
+	}
+	return pausedBy
+}
@@ -0,0 +1,81 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"strings"
+)
+
+// Labels and annotations of the operator itself are never propagated
+const operatorKeyPrefix = "application.ops.csas.cz/"
+
+// Propagation configuration, read from env vars
+var propagatedLabelPrefixes []string
+var propagatedAnnotationPrefixes []string
+var propagatedNamespaceLabels []string
+
+// Returns labels and annotations of the CR and its namespace, which should be propagated to generated applications
+func (r *ReconcileApplication) propagatedMetadata(ctx context.Context, cr *opsv1alpha1.Application) (map[string]string, map[string]string, error) {
+	labels := filterByPrefix(cr.Labels, propagatedLabelPrefixes)
+	annotations := filterByPrefix(cr.Annotations, propagatedAnnotationPrefixes)
+
+	if len(propagatedNamespaceLabels) > 0 {
+		ns := &corev1.Namespace{}
+		if err := r.client.Get(ctx, types.NamespacedName{Name: cr.Namespace}, ns); err != nil {
+			return nil, nil, fmt.Errorf("failed to get namespace %s: %w", cr.Namespace, err)
+		}
+
+		for _, label := range propagatedNamespaceLabels {
+			// Labels of the CR take precedence
+			if _, ok := labels[label]; !ok && !strings.HasPrefix(label, operatorKeyPrefix) {
+				if value, ok := ns.Labels[label]; ok {
+					labels[label] = value
+				}
+			}
+		}
+	}
+
+	return labels, annotations, nil
+}
+
+func filterByPrefix(values map[string]string, prefixes []string) map[string]string {
+	result := map[string]string{}
+	for key, value := range values {
+		if strings.HasPrefix(key, operatorKeyPrefix) {
+			continue
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				result[key] = value
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Sets propagated labels and annotations on the target object
+func setPropagatedMetadata(obj metav1.Object, labels, annotations map[string]string) {
+	obj.SetLabels(mergeMissing(obj.GetLabels(), labels))
+	obj.SetAnnotations(mergeMissing(obj.GetAnnotations(), annotations))
+}
+
+// Adds values which are not present in target yet, so operator values take precedence
+func mergeMissing(target, values map[string]string) map[string]string {
+	if len(values) == 0 {
+		return target
+	}
+	if target == nil {
+		target = make(map[string]string, len(values))
+	}
+	for key, value := range values {
+		if _, ok := target[key]; !ok {
+			target[key] = value
+		}
+	}
+	return target
+}
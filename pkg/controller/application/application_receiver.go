@@ -0,0 +1,136 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-logr/logr"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"github.com/mdvorak/argo-application-operator/pkg/httpserver"
+	"github.com/mdvorak/argo-application-operator/pkg/receiver"
+	"k8s.io/apimachinery/pkg/runtime"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// Name of the field index of Application.ops.csas.cz, containing normalized spec.source.repoURL of git sources
+const repoURLField = "spec.source.repoURL"
+
+// Indexer function for repoURLField
+func repoURLIndexer(obj runtime.Object) []string {
+	src := obj.(*opsv1alpha1.Application).Spec.Source
+	// Helm repositories don't receive git pushes
+	if src.Chart != "" {
+		return nil
+	}
+	if repoURL := receiver.NormalizeRepoURL(src.RepoURL); repoURL != "" {
+		return []string{repoURL}
+	}
+	return nil
+}
+
+// Handles git push webhooks, and refreshes targets of all applications using pushed repository and revision
+type pushReceiver struct {
+	client  client.Client
+	backend backend
+	secret  string
+}
+
+// Adds push webhook receiver to the manager, if it is enabled
+func addReceiver(mgr manager.Manager, b backend) error {
+	enabled, err := receiver.GetEnabled()
+	if err != nil || !enabled {
+		return err
+	}
+	port, err := receiver.GetPort()
+	if err != nil {
+		return err
+	}
+	secret, err := receiver.GetSecret()
+	if err != nil {
+		return fmt.Errorf("webhook receiver requires secret: %w", err)
+	}
+
+	return mgr.Add(&httpserver.Server{
+		Name:    "webhook receiver",
+		Port:    port,
+		Path:    receiver.ReceiverPath,
+		Handler: &pushReceiver{client: mgr.GetClient(), backend: b, secret: secret},
+	})
+}
+
+// ServeHTTP implements http.Handler
+func (h *pushReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	event, err := receiver.ParsePush(req, h.secret)
+	if errors.Is(err, receiver.ErrUnauthorized) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	} else if errors.Is(err, receiver.ErrUnsupportedEvent) {
+		// Acknowledge, so the git server does not report failed deliveries
+		w.WriteHeader(http.StatusAccepted)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	refreshed, err := h.refresh(context.TODO(), event)
+	if err != nil {
+		log.Error(err, "failed to refresh applications on push", "RepoURLs", event.RepoURLs, "Revisions", event.Revisions)
+		http.Error(w, "failed to refresh applications", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprintf(w, "refreshed %d applications\n", refreshed)
+}
+
+// Refreshes targets of all applications matching the push event, returns number of refreshed applications
+func (h *pushReceiver) refresh(ctx context.Context, event *receiver.PushEvent) (int, error) {
+	// Repository URLs of the event are usually different forms of the same URL
+	seen := make(map[string]bool)
+	refreshed := 0
+	var lastErr error
+
+	for _, repoURL := range event.RepoURLs {
+		repoURL = receiver.NormalizeRepoURL(repoURL)
+		if repoURL == "" || seen[repoURL] {
+			continue
+		}
+		seen[repoURL] = true
+
+		list := &opsv1alpha1.ApplicationList{}
+		if err := h.client.List(ctx, list, client.MatchingFields{repoURLField: repoURL}); err != nil {
+			return refreshed, fmt.Errorf("failed to list Application.ops.csas.cz: %w", err)
+		}
+
+		for i := range list.Items {
+			cr := &list.Items[i]
+			if cr.DeletionTimestamp != nil || !event.MatchesRevision(cr.Spec.Source.TargetRevision) {
+				continue
+			}
+
+			logger := log.WithValues("Request.Namespace", cr.Namespace, "Request.Name", cr.Name)
+			if err := h.refreshApplication(ctx, logger, cr); err != nil {
+				// Try the rest anyway
+				logger.Error(err, "failed to refresh targets")
+				lastErr = err
+				continue
+			}
+			refreshed++
+		}
+	}
+
+	return refreshed, lastErr
+}
+
+func (h *pushReceiver) refreshApplication(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application) error {
+	logger.Info("refreshing targets on push")
+	return h.backend.refresh(ctx, logger, cr)
+}
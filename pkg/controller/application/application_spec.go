@@ -3,25 +3,34 @@ package application
 import (
 	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
 	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"github.com/mdvorak/argo-application-operator/pkg/argocd"
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"reflect"
+	"strconv"
 	"strings"
 )
 
-func newApplication(cr *opsv1alpha1.Application) *argocdv1alpha1.Application {
+// Whether annotations recorded by the admission webhook can be trusted. Without the webhook, users can set them freely.
+var webhookAnnotationsTrusted bool
+
+// Returns name of target objects, which is prefixed with CR namespace to avoid conflicts
+func targetName(cr *opsv1alpha1.Application) string {
 	name := cr.Name
 	if !strings.HasPrefix(name, cr.Namespace+"-") {
 		name = cr.Namespace + "-" + cr.Name
 	}
+	return name
+}
 
+func (b *argocdBackend) newApplication(cr *opsv1alpha1.Application, instance *argocd.Instance) *argocdv1alpha1.Application {
 	return &argocdv1alpha1.Application{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      name,
-			Namespace: argoNamespace,
-			Labels:    applicationLabels(cr),
+			Name:        b.targetName(cr),
+			Namespace:   instance.Namespace,
+			Labels:      b.applicationLabels(cr),
+			Annotations: applicationAnnotations(cr),
 		},
-		Spec: newApplicationSpec(cr),
+		Spec: b.newApplicationSpec(cr, instance),
 	}
 }
 
@@ -46,14 +55,31 @@ func applicationLabels(owner *opsv1alpha1.Application) map[string]string {
 	return labels
 }
 
-func newApplicationSpec(cr *opsv1alpha1.Application) argocdv1alpha1.ApplicationSpec {
+// Returns annotations of target objects, which record who requested and approved the deployment, for audit purposes
+func applicationAnnotations(owner *opsv1alpha1.Application) map[string]string {
+	annotations := map[string]string{
+		sourceGenerationAnnotation: strconv.FormatInt(owner.Generation, 10),
+		sourceUIDAnnotation:        string(owner.UID),
+	}
+	if !webhookAnnotationsTrusted {
+		return annotations
+	}
+	for _, annotation := range opsv1alpha1.WebhookAnnotations {
+		if value, ok := owner.Annotations[annotation]; ok {
+			annotations[annotation] = value
+		}
+	}
+	return annotations
+}
+
+func (b *argocdBackend) newApplicationSpec(cr *opsv1alpha1.Application, instance *argocd.Instance) argocdv1alpha1.ApplicationSpec {
 	return argocdv1alpha1.ApplicationSpec{
 		Source: cr.Spec.Source,
 		Destination: argocdv1alpha1.ApplicationDestination{
-			Server:    destinationServer,
-			Namespace: cr.Namespace,
+			Server:    instance.DestinationServer,
+			Namespace: cr.DestinationNamespace(),
 		},
-		Project:              cr.Namespace,
+		Project:              instance.ProjectName(cr.Namespace),
 		SyncPolicy:           cr.Spec.SyncPolicy,
 		IgnoreDifferences:    cr.Spec.IgnoreDifferences,
 		Info:                 cr.Spec.Info,
@@ -61,36 +87,12 @@ func newApplicationSpec(cr *opsv1alpha1.Application) argocdv1alpha1.ApplicationS
 	}
 }
 
-func patchApplication(obj *argocdv1alpha1.Application, source *argocdv1alpha1.Application) (change bool) {
-	// Compare and update labels and annotations
-	for label, value := range source.Labels {
-		if obj.Labels[label] != value {
-			obj.Labels[label] = value
-			change = true
-		}
-	}
-
-	for annotation, value := range source.Annotations {
-		if obj.Annotations[annotation] != value {
-			obj.Annotations[annotation] = value
-			change = true
-		}
-	}
-
-	// Compare and update spec
-	if change || !reflect.DeepEqual(obj.Spec, source.Spec) {
-		obj.Spec = source.Spec
-		change = true
-	}
-
-	return
-}
-
-func isApplicationOwnedBy(obj *argocdv1alpha1.Application, owner *opsv1alpha1.Application) bool {
+func isApplicationOwnedBy(obj metav1.Object, owner *opsv1alpha1.Application) bool {
 	gvk := owner.GroupVersionKind()
-	return obj.Labels[ownerApiGroupLabel] != gvk.Group ||
-		obj.Labels[ownerApiVersionLabel] != gvk.Version ||
-		obj.Labels[ownerKindLabel] != gvk.Kind ||
-		obj.Labels[ownerNamespaceLabel] != owner.Namespace ||
-		obj.Labels[ownerNameLabel] != owner.Name
+	labels := obj.GetLabels()
+	return labels[ownerApiGroupLabel] != gvk.Group ||
+		labels[ownerApiVersionLabel] != gvk.Version ||
+		labels[ownerKindLabel] != gvk.Kind ||
+		labels[ownerNamespaceLabel] != owner.Namespace ||
+		labels[ownerNameLabel] != owner.Name
 }
@@ -0,0 +1,374 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	"github.com/go-logr/logr"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"github.com/robfig/cron"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const syncWindowAllow = "allow"
+const syncWindowDeny = "deny"
+
+// Annotation of AppProject.argoproj.io, containing JSON list of sync windows managed by the operator
+const managedSyncWindowsAnnotation = "application.ops.csas.cz/managed-sync-windows"
+
+// Same parser as used by Argo CD
+var syncWindowParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+
+// Parsed SyncWindow
+type syncWindow struct {
+	opsv1alpha1.SyncWindow
+	schedule cron.Schedule
+	duration time.Duration
+	location *time.Location
+}
+
+func parseSyncWindow(w opsv1alpha1.SyncWindow) (*syncWindow, error) {
+	if w.Kind != syncWindowAllow && w.Kind != syncWindowDeny {
+		return nil, fmt.Errorf("sync window kind '%s' mismatch: can only be %s or %s", w.Kind, syncWindowAllow, syncWindowDeny)
+	}
+
+	schedule, err := syncWindowParser.Parse(w.Schedule)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse sync window schedule '%s': %w", w.Schedule, err)
+	}
+
+	duration, err := time.ParseDuration(w.Duration)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse sync window duration '%s': %w", w.Duration, err)
+	}
+
+	location, err := time.LoadLocation(w.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown sync window time zone '%s': %w", w.TimeZone, err)
+	}
+
+	return &syncWindow{SyncWindow: w, schedule: schedule, duration: duration, location: location}, nil
+}
+
+// Returns start of the window, if it is active at given time
+func (w *syncWindow) activeSince(now time.Time) (time.Time, bool) {
+	start := w.schedule.Next(now.In(w.location).Add(-w.duration))
+	return start, !start.After(now)
+}
+
+// Returns time when the window opens next time after given time
+func (w *syncWindow) next(now time.Time) time.Time {
+	return w.schedule.Next(now.In(w.location))
+}
+
+// Renders the window as Argo CD project sync window for single application.
+// Argo CD evaluates schedules in UTC, so schedule is converted to it.
+func (w *syncWindow) toArgo(appName string, now time.Time) (*argocdv1alpha1.SyncWindow, error) {
+	schedule, err := scheduleInUTC(w.Schedule, w.location, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &argocdv1alpha1.SyncWindow{
+		Kind:         w.Kind,
+		Schedule:     schedule,
+		Duration:     w.Duration,
+		Applications: []string{appName},
+		ManualSync:   w.ManualSync,
+	}, nil
+}
+
+// Evaluates given windows, returning whether automated sync is allowed at given time,
+// and when the next window opens.
+func evaluateSyncWindows(windows []*syncWindow, now time.Time) (bool, *time.Time) {
+	var denyActive, allowActive, hasAllow bool
+	var next *time.Time
+
+	for _, w := range windows {
+		_, active := w.activeSince(now)
+		switch w.Kind {
+		case syncWindowAllow:
+			hasAllow = true
+			allowActive = allowActive || active
+		case syncWindowDeny:
+			denyActive = denyActive || active
+		}
+
+		if n := w.next(now); next == nil || n.Before(*next) {
+			next = &n
+		}
+	}
+
+	return !denyActive && (!hasAllow || allowActive), next
+}
+
+// Converts 5-field cron schedule in given location to UTC, using its current offset.
+// Only whole hour offsets and explicit hours are supported, and when day changes, day of month and month must not be
+// restricted. Hours may be omitted only when days are not restricted at all.
+func scheduleInUTC(schedule string, location *time.Location, now time.Time) (string, error) {
+	_, offset := now.In(location).Zone()
+	if offset == 0 {
+		return schedule, nil
+	}
+	if offset%3600 != 0 {
+		return "", fmt.Errorf("time zone %s is not supported, its offset is not whole hours", location)
+	}
+
+	fields := strings.Fields(schedule)
+	if len(fields) != 5 {
+		return "", fmt.Errorf("schedule '%s' must have exactly 5 fields when time zone is set", schedule)
+	}
+	if fields[1] == "*" {
+		// Every hour, nothing to shift, unless days are restricted - their boundary is at different time in UTC
+		if fields[2] != "*" || fields[3] != "*" || fields[4] != "*" {
+			return "", fmt.Errorf("cannot convert schedule '%s' to UTC, hours must be explicit when days are restricted", schedule)
+		}
+		return schedule, nil
+	}
+
+	hours, err := expandCronField(fields[1], 0, 23)
+	if err != nil {
+		return "", fmt.Errorf("cannot convert schedule '%s' to UTC: %w", schedule, err)
+	}
+
+	// Shift hours
+	dayShift := 0
+	shifted := make([]string, len(hours))
+	for i, h := range hours {
+		h -= offset / 3600
+		d := 0
+		if h < 0 {
+			h, d = h+24, -1
+		} else if h > 23 {
+			h, d = h-24, 1
+		}
+		if i > 0 && d != dayShift {
+			return "", fmt.Errorf("cannot convert schedule '%s' to UTC, hours would fall into different days", schedule)
+		}
+		dayShift = d
+		shifted[i] = strconv.Itoa(h)
+	}
+	fields[1] = strings.Join(shifted, ",")
+
+	// Shift days
+	if dayShift != 0 {
+		if fields[2] != "*" || fields[3] != "*" {
+			return "", fmt.Errorf("cannot convert schedule '%s' to UTC, day of month would change", schedule)
+		}
+		if fields[4] != "*" {
+			days, err := expandCronField(fields[4], 0, 7)
+			if err != nil {
+				return "", fmt.Errorf("cannot convert schedule '%s' to UTC: %w", schedule, err)
+			}
+			for i, d := range days {
+				days[i] = (d + dayShift + 7) % 7
+			}
+			fields[4] = joinInts(days)
+		}
+	}
+
+	return strings.Join(fields, " "), nil
+}
+
+// Expands cron field consisting of numbers and ranges into list of numbers
+func expandCronField(field string, min, max int) ([]int, error) {
+	var values []int
+	for _, part := range strings.Split(field, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		from, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("only numbers and ranges are supported, got '%s'", part)
+		}
+		to := from
+		if len(bounds) == 2 {
+			if to, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("only numbers and ranges are supported, got '%s'", part)
+			}
+		}
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("value '%s' is out of range %d-%d", part, min, max)
+		}
+		for v := from; v <= to; v++ {
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+func joinInts(values []int) string {
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, ",")
+}
+
+// Renders sync windows of the CR into its AppProject and returns their state, nil if there are none.
+// Schedules next reconcile when the next window opens.
+func (b *argocdBackend) reconcileSyncWindows(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application, app *argocdv1alpha1.Application) (reconcile.Result, *opsv1alpha1.SyncWindowStatus, error) {
+	now := time.Now()
+
+	// Parse
+	windows := make([]*syncWindow, 0, len(cr.Spec.SyncWindows))
+	argoWindows := make(argocdv1alpha1.SyncWindows, 0, len(cr.Spec.SyncWindows))
+	for _, w := range cr.Spec.SyncWindows {
+		parsed, err := parseSyncWindow(w)
+		if err != nil {
+			return reconcile.Result{}, nil, newInvalidSpecError("invalid sync window: %w", err)
+		}
+		argoWindow, err := parsed.toArgo(app.Name, now)
+		if err != nil {
+			return reconcile.Result{}, nil, newInvalidSpecError("invalid sync window: %w", err)
+		}
+
+		windows = append(windows, parsed)
+		argoWindows = append(argoWindows, argoWindow)
+	}
+
+	// Render into project
+	if err := b.updateProjectSyncWindows(ctx, logger, app, argoWindows); err != nil {
+		return reconcile.Result{}, nil, err
+	}
+
+	// Report
+	if len(windows) == 0 {
+		return reconcile.Result{}, nil, nil
+	}
+
+	allowed, next := evaluateSyncWindows(windows, now)
+	windowStatus := &opsv1alpha1.SyncWindowStatus{SyncAllowed: allowed}
+	if next != nil {
+		nextWindowAt := metav1.NewTime(next.Local().Truncate(time.Second))
+		windowStatus.NextWindowAt = &nextWindowAt
+	}
+
+	// Requeue when the next window opens or any active closes, so status stays current.
+	// Also at least daily, since conversion to UTC may change with daylight saving time.
+	requeueAfter := 24 * time.Hour
+	if next != nil && next.Sub(now) < requeueAfter {
+		requeueAfter = next.Sub(now)
+	}
+	for _, w := range windows {
+		if start, active := w.activeSince(now); active && start.Add(w.duration).Sub(now) < requeueAfter {
+			requeueAfter = start.Add(w.duration).Sub(now)
+		}
+	}
+	return reconcile.Result{RequeueAfter: requeueAfter + time.Second}, windowStatus, nil
+}
+
+// Replaces sync windows of given application in its AppProject. Windows rendered by the operator are recorded
+// in managedSyncWindowsAnnotation of the project, other windows are never modified.
+func (b *argocdBackend) updateProjectSyncWindows(ctx context.Context, logger logr.Logger, app *argocdv1alpha1.Application, windows argocdv1alpha1.SyncWindows) error {
+	project := &argocdv1alpha1.AppProject{}
+	err := b.client.Get(ctx, types.NamespacedName{Name: app.Spec.Project, Namespace: app.Namespace}, project)
+	if err != nil && k8serrors.IsNotFound(err) {
+		if len(windows) == 0 {
+			// Nothing to remove
+			return nil
+		}
+		return newProjectMissingError("AppProject.argocd.io \"%s\" for sync windows does not exist in namespace \"%s\"", app.Spec.Project, app.Namespace)
+	} else if err != nil {
+		return fmt.Errorf("failed to get AppProject.argocd.io: %w", err)
+	}
+
+	managed, err := managedSyncWindows(project)
+	if err != nil {
+		return err
+	}
+
+	// Keep windows not managed by us, and managed windows of other applications
+	newWindows := make(argocdv1alpha1.SyncWindows, 0, len(project.Spec.SyncWindows)+len(windows))
+	newManaged := make(argocdv1alpha1.SyncWindows, 0, len(managed)+len(windows))
+	for _, w := range project.Spec.SyncWindows {
+		if !containsSyncWindow(managed, w) {
+			newWindows = append(newWindows, w)
+		} else if len(w.Applications) != 1 || w.Applications[0] != app.Name {
+			newWindows = append(newWindows, w)
+			newManaged = append(newManaged, w)
+		}
+	}
+	for _, w := range windows {
+		// Identical window, e.g. rendered before windows were recorded, is adopted instead of duplicated
+		if !containsSyncWindow(newWindows, w) {
+			newWindows = append(newWindows, w)
+		}
+	}
+	newManaged = append(newManaged, windows...)
+
+	newProject := project.DeepCopy()
+	newProject.Spec.SyncWindows = newWindows
+	if err := setManagedSyncWindows(newProject, newManaged); err != nil {
+		return err
+	}
+
+	if newProject.Annotations[managedSyncWindowsAnnotation] == project.Annotations[managedSyncWindowsAnnotation] &&
+		(reflect.DeepEqual(project.Spec.SyncWindows, newWindows) || (len(project.Spec.SyncWindows) == 0 && len(newWindows) == 0)) {
+		return nil
+	}
+
+	logger.Info("updating sync windows of AppProject.argocd.io", "AppProject.Name", project.Name)
+	if err := b.client.Patch(ctx, newProject, client.MergeFrom(project)); err != nil {
+		return fmt.Errorf("failed to update sync windows of AppProject.argocd.io: %w", err)
+	}
+	return nil
+}
+
+// Returns sync windows of the project, which are managed by the operator
+func managedSyncWindows(project *argocdv1alpha1.AppProject) (argocdv1alpha1.SyncWindows, error) {
+	value := project.Annotations[managedSyncWindowsAnnotation]
+	if value == "" {
+		return nil, nil
+	}
+
+	var windows argocdv1alpha1.SyncWindows
+	if err := json.Unmarshal([]byte(value), &windows); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation of AppProject.argocd.io \"%s\": %w", managedSyncWindowsAnnotation, project.Name, err)
+	}
+	return windows, nil
+}
+
+// Records sync windows of the project, which are managed by the operator
+func setManagedSyncWindows(project *argocdv1alpha1.AppProject, windows argocdv1alpha1.SyncWindows) error {
+	if len(windows) == 0 {
+		delete(project.Annotations, managedSyncWindowsAnnotation)
+		return nil
+	}
+
+	value, err := json.Marshal(windows)
+	if err != nil {
+		return fmt.Errorf("failed to serialize managed sync windows: %w", err)
+	}
+	if project.Annotations == nil {
+		project.Annotations = make(map[string]string)
+	}
+	project.Annotations[managedSyncWindowsAnnotation] = string(value)
+	return nil
+}
+
+func containsSyncWindow(windows argocdv1alpha1.SyncWindows, window *argocdv1alpha1.SyncWindow) bool {
+	for _, w := range windows {
+		if reflect.DeepEqual(w, window) {
+			return true
+		}
+	}
+	return false
+}
+
+func equalSyncWindowStatus(a, b *opsv1alpha1.SyncWindowStatus) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.NextWindowAt == nil || b.NextWindowAt == nil {
+		return a.SyncAllowed == b.SyncAllowed && a.NextWindowAt == b.NextWindowAt
+	}
+	return a.SyncAllowed == b.SyncAllowed && a.NextWindowAt.Equal(b.NextWindowAt)
+}
@@ -0,0 +1,180 @@
+package application
+
+import (
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestScheduleInUTC(t *testing.T) {
+	now := time.Date(2020, 3, 25, 10, 30, 0, 0, time.UTC)
+	cet := time.FixedZone("CET", 3600)
+	west := time.FixedZone("WEST", -3600)
+	india := time.FixedZone("IST", 5*3600+1800)
+
+	tests := []struct {
+		schedule string
+		location *time.Location
+		expected string
+		err      bool
+	}{
+		{schedule: "0 22 * * 1", location: time.UTC, expected: "0 22 * * 1"},
+		{schedule: "0 22 * * *", location: cet, expected: "0 21 * * *"},
+		{schedule: "30 1-3 * * *", location: cet, expected: "30 0,1,2 * * *"},
+		{schedule: "0 0 * * 1", location: cet, expected: "0 23 * * 0"},
+		{schedule: "0 0 * * 0,6", location: cet, expected: "0 23 * * 6,5"},
+		{schedule: "0 23 * * 6", location: west, expected: "0 0 * * 0"},
+		{schedule: "0 12 1 * *", location: cet, expected: "0 11 1 * *"},
+		{schedule: "* * * * *", location: cet, expected: "* * * * *"},
+		{schedule: "* * * * 1", location: cet, err: true},
+		{schedule: "* * 1 * *", location: cet, err: true},
+		{schedule: "0 0-1 * * *", location: cet, err: true},
+		{schedule: "0 0 1 * *", location: cet, err: true},
+		{schedule: "0 0 * 1 *", location: cet, err: true},
+		{schedule: "0 */2 * * *", location: cet, err: true},
+		{schedule: "0 10 * *", location: cet, err: true},
+		{schedule: "0 10 * * *", location: india, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schedule+" "+tt.location.String(), func(t *testing.T) {
+			actual, err := scheduleInUTC(tt.schedule, tt.location, now)
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected error, got '%s'", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != tt.expected {
+				t.Errorf("expected '%s', got '%s'", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestExpandCronField(t *testing.T) {
+	tests := []struct {
+		field    string
+		expected []int
+		err      bool
+	}{
+		{field: "5", expected: []int{5}},
+		{field: "1,3-5", expected: []int{1, 3, 4, 5}},
+		{field: "0-2,23", expected: []int{0, 1, 2, 23}},
+		{field: "*/2", err: true},
+		{field: "mon", err: true},
+		{field: "1-x", err: true},
+		{field: "5-3", err: true},
+		{field: "24", err: true},
+		{field: "-1", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			actual, err := expandCronField(tt.field, 0, 23)
+			if tt.err {
+				if err == nil {
+					t.Errorf("expected error, got %v", actual)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(actual, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestEvaluateSyncWindows(t *testing.T) {
+	now := time.Date(2020, 3, 25, 10, 30, 0, 0, time.UTC)
+	at := func(hour int) *time.Time {
+		t := time.Date(2020, 3, 25, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+	tomorrowAt := func(hour int) *time.Time {
+		t := time.Date(2020, 3, 26, hour, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	tests := []struct {
+		name            string
+		windows         []opsv1alpha1.SyncWindow
+		expectedAllowed bool
+		expectedNext    *time.Time
+	}{
+		{
+			name:            "no windows",
+			expectedAllowed: true,
+		},
+		{
+			name:            "active allow",
+			windows:         []opsv1alpha1.SyncWindow{{Kind: syncWindowAllow, Schedule: "0 10 * * *", Duration: "1h"}},
+			expectedAllowed: true,
+			expectedNext:    tomorrowAt(10),
+		},
+		{
+			name:            "inactive allow",
+			windows:         []opsv1alpha1.SyncWindow{{Kind: syncWindowAllow, Schedule: "0 12 * * *", Duration: "1h"}},
+			expectedAllowed: false,
+			expectedNext:    at(12),
+		},
+		{
+			name:            "active deny",
+			windows:         []opsv1alpha1.SyncWindow{{Kind: syncWindowDeny, Schedule: "0 9 * * *", Duration: "2h"}},
+			expectedAllowed: false,
+			expectedNext:    tomorrowAt(9),
+		},
+		{
+			name:            "inactive deny",
+			windows:         []opsv1alpha1.SyncWindow{{Kind: syncWindowDeny, Schedule: "0 11 * * *", Duration: "1h"}},
+			expectedAllowed: true,
+			expectedNext:    at(11),
+		},
+		{
+			name: "deny wins over allow",
+			windows: []opsv1alpha1.SyncWindow{
+				{Kind: syncWindowAllow, Schedule: "0 10 * * *", Duration: "1h"},
+				{Kind: syncWindowDeny, Schedule: "15 10 * * *", Duration: "1h"},
+			},
+			expectedAllowed: false,
+			expectedNext:    tomorrowAt(10),
+		},
+		{
+			name: "any active allow",
+			windows: []opsv1alpha1.SyncWindow{
+				{Kind: syncWindowAllow, Schedule: "0 10 * * *", Duration: "1h"},
+				{Kind: syncWindowAllow, Schedule: "0 14 * * *", Duration: "1h"},
+			},
+			expectedAllowed: true,
+			expectedNext:    at(14),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			windows := make([]*syncWindow, 0, len(tt.windows))
+			for _, w := range tt.windows {
+				parsed, err := parseSyncWindow(w)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				windows = append(windows, parsed)
+			}
+
+			allowed, next := evaluateSyncWindows(windows, now)
+			if allowed != tt.expectedAllowed {
+				t.Errorf("expected allowed %v, got %v", tt.expectedAllowed, allowed)
+			}
+			if (next == nil) != (tt.expectedNext == nil) || (next != nil && !next.Equal(*tt.expectedNext)) {
+				t.Errorf("expected next %v, got %v", tt.expectedNext, next)
+			}
+		})
+	}
+}
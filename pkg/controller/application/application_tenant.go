@@ -0,0 +1,253 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	"github.com/go-logr/logr"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sort"
+)
+
+// Label of namespaces, namespaces with the same value are owned by the same tenant
+const tenantLabel = "application.ops.csas.cz/tenant"
+
+// Annotation of AppProject.argoproj.io, containing JSON list of destinations managed by the operator
+const managedDestinationsAnnotation = "application.ops.csas.cz/managed-destinations"
+
+// Name of the field index of Application.ops.csas.cz, containing effective destination namespace
+const destinationNamespaceField = "spec.destination.namespace"
+
+// Indexer function for destinationNamespaceField
+func destinationNamespaceIndexer(obj runtime.Object) []string {
+	return []string{obj.(*opsv1alpha1.Application).DestinationNamespace()}
+}
+
+// Maps Namespace to all Application.ops.csas.cz in it, and all applications deploying into it, as its labels and
+// annotations define tenant, Argo CD instance and drift policy of the applications
+type namespaceApplicationsMapper struct {
+	client client.Client
+}
+
+// Map implements handler.Mapper
+func (m *namespaceApplicationsMapper) Map(obj handler.MapObject) []reconcile.Request {
+	requests := []reconcile.Request{}
+	seen := map[types.NamespacedName]bool{}
+
+	for _, opts := range [][]client.ListOption{
+		{client.InNamespace(obj.Meta.GetName())},
+		{client.MatchingFields{destinationNamespaceField: obj.Meta.GetName()}},
+	} {
+		list := &opsv1alpha1.ApplicationList{}
+		if err := m.client.List(context.TODO(), list, opts...); err != nil {
+			log.Error(err, "failed to list Application.ops.csas.cz of namespace", "Namespace", obj.Meta.GetName())
+			return []reconcile.Request{}
+		}
+		for _, item := range list.Items {
+			name := types.NamespacedName{Name: item.Name, Namespace: item.Namespace}
+			if !seen[name] {
+				seen[name] = true
+				requests = append(requests, reconcile.Request{NamespacedName: name})
+			}
+		}
+	}
+	return requests
+}
+
+// Detect update of Namespace only when its labels or annotations change
+var namespaceMetadataChangedPredicate = predicate.Funcs{
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		return !reflect.DeepEqual(e.MetaNew.GetLabels(), e.MetaOld.GetLabels()) ||
+			!reflect.DeepEqual(e.MetaNew.GetAnnotations(), e.MetaOld.GetAnnotations())
+	},
+}
+
+// Verifies that destination namespace is owned by the same tenant as the CR namespace.
+// Returns all namespaces of the tenant, when any application in the CR namespace deploys into other namespace, as they
+// share the project. Otherwise returns empty list, so stale destinations are removed from the project.
+func (r *ReconcileApplication) checkDestination(ctx context.Context, cr *opsv1alpha1.Application) ([]string, error) {
+	crossNamespace, err := r.deploysCrossNamespace(ctx, cr)
+	if err != nil || !crossNamespace {
+		return []string{}, err
+	}
+
+	namespaces, err := r.tenantNamespaces(ctx, cr.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if destination := cr.DestinationNamespace(); !contains(namespaces, destination) {
+		return nil, newPolicyViolationError("destination namespace %s is not owned by the same tenant as namespace %s", destination, cr.Namespace)
+	}
+	return namespaces, nil
+}
+
+// Returns true when the CR, or any other application in its namespace, deploys outside of the namespace
+func (r *ReconcileApplication) deploysCrossNamespace(ctx context.Context, cr *opsv1alpha1.Application) (bool, error) {
+	if cr.DestinationNamespace() != cr.Namespace {
+		return true, nil
+	}
+
+	list := &opsv1alpha1.ApplicationList{}
+	if err := r.client.List(ctx, list, client.InNamespace(cr.Namespace)); err != nil {
+		return false, fmt.Errorf("failed to list Application.ops.csas.cz in namespace %s: %w", cr.Namespace, err)
+	}
+	for _, item := range list.Items {
+		if item.Name != cr.Name && item.DeletionTimestamp == nil && item.DestinationNamespace() != item.Namespace {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Returns sorted namespaces owned by the same tenant as given namespace, including the namespace itself.
+// Tenant is defined either by the namespace label, or by Tenant.ops.csas.cz objects.
+func (r *ReconcileApplication) tenantNamespaces(ctx context.Context, namespace string) ([]string, error) {
+	namespaces := []string{namespace}
+
+	// Tenant label
+	ns := &corev1.Namespace{}
+	if err := r.client.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return nil, fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+	}
+	if tenant := ns.Labels[tenantLabel]; len(tenant) > 0 {
+		list := &corev1.NamespaceList{}
+		if err := r.client.List(ctx, list, client.MatchingLabels{tenantLabel: tenant}); err != nil {
+			return nil, fmt.Errorf("failed to list namespaces of tenant %s: %w", tenant, err)
+		}
+		for _, item := range list.Items {
+			if !contains(namespaces, item.Name) {
+				namespaces = append(namespaces, item.Name)
+			}
+		}
+	}
+
+	// Tenant objects
+	tenants := &opsv1alpha1.TenantList{}
+	if err := r.client.List(ctx, tenants); err != nil {
+		return nil, fmt.Errorf("failed to list Tenant.ops.csas.cz: %w", err)
+	}
+	for _, tenant := range tenants.Items {
+		if !contains(tenant.Spec.Namespaces, namespace) {
+			continue
+		}
+		for _, item := range tenant.Spec.Namespaces {
+			if !contains(namespaces, item) {
+				namespaces = append(namespaces, item)
+			}
+		}
+	}
+
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// Replaces destinations of the tenant in the AppProject of the application. Destinations added by the operator are
+// recorded in managedDestinationsAnnotation of the project, and removed once their namespace leaves the tenant.
+// Other destinations are never modified.
+func (b *argocdBackend) updateProjectDestinations(ctx context.Context, logger logr.Logger, app *argocdv1alpha1.Application, server string, namespaces []string) error {
+	project := &argocdv1alpha1.AppProject{}
+	err := b.client.Get(ctx, types.NamespacedName{Name: app.Spec.Project, Namespace: app.Namespace}, project)
+	if err != nil && k8serrors.IsNotFound(err) && len(namespaces) == 0 {
+		// Nothing to add, nor to prune
+		return nil
+	} else if err != nil && k8serrors.IsNotFound(err) {
+		return newProjectMissingError("AppProject.argocd.io \"%s\" for destinations does not exist in namespace \"%s\"", app.Spec.Project, app.Namespace)
+	} else if err != nil {
+		return fmt.Errorf("failed to get AppProject.argocd.io: %w", err)
+	}
+
+	managed, err := managedDestinations(project)
+	if err != nil {
+		return err
+	}
+
+	// Keep destinations not managed by us, and managed destinations still owned by the tenant
+	newDestinations := make([]argocdv1alpha1.ApplicationDestination, 0, len(project.Spec.Destinations)+len(namespaces))
+	newManaged := make([]argocdv1alpha1.ApplicationDestination, 0, len(managed)+len(namespaces))
+	for _, d := range project.Spec.Destinations {
+		if !containsDestination(managed, d.Server, d.Namespace) {
+			newDestinations = append(newDestinations, d)
+		} else if contains(namespaces, d.Namespace) {
+			newDestinations = append(newDestinations, d)
+			newManaged = append(newManaged, d)
+		}
+	}
+	for _, namespace := range namespaces {
+		// Identical destination, e.g. added before destinations were recorded, is adopted instead of duplicated
+		if !containsDestination(newDestinations, server, namespace) {
+			newDestinations = append(newDestinations, argocdv1alpha1.ApplicationDestination{Server: server, Namespace: namespace})
+		}
+		if !containsDestination(newManaged, server, namespace) {
+			newManaged = append(newManaged, argocdv1alpha1.ApplicationDestination{Server: server, Namespace: namespace})
+		}
+	}
+
+	newProject := project.DeepCopy()
+	newProject.Spec.Destinations = newDestinations
+	if err := setManagedDestinations(newProject, newManaged); err != nil {
+		return err
+	}
+
+	if newProject.Annotations[managedDestinationsAnnotation] == project.Annotations[managedDestinationsAnnotation] &&
+		(reflect.DeepEqual(project.Spec.Destinations, newDestinations) || (len(project.Spec.Destinations) == 0 && len(newDestinations) == 0)) {
+		return nil
+	}
+
+	logger.Info("updating destinations of AppProject.argocd.io", "AppProject.Name", project.Name)
+	if err := b.client.Patch(ctx, newProject, client.MergeFrom(project)); err != nil {
+		return fmt.Errorf("failed to update destinations of AppProject.argocd.io: %w", err)
+	}
+	return nil
+}
+
+// Returns destinations of the project, which are managed by the operator
+func managedDestinations(project *argocdv1alpha1.AppProject) ([]argocdv1alpha1.ApplicationDestination, error) {
+	value := project.Annotations[managedDestinationsAnnotation]
+	if value == "" {
+		return nil, nil
+	}
+
+	var destinations []argocdv1alpha1.ApplicationDestination
+	if err := json.Unmarshal([]byte(value), &destinations); err != nil {
+		return nil, fmt.Errorf("invalid %s annotation of AppProject.argocd.io \"%s\": %w", managedDestinationsAnnotation, project.Name, err)
+	}
+	return destinations, nil
+}
+
+// Records destinations of the project, which are managed by the operator
+func setManagedDestinations(project *argocdv1alpha1.AppProject, destinations []argocdv1alpha1.ApplicationDestination) error {
+	if len(destinations) == 0 {
+		delete(project.Annotations, managedDestinationsAnnotation)
+		return nil
+	}
+
+	value, err := json.Marshal(destinations)
+	if err != nil {
+		return fmt.Errorf("failed to serialize managed destinations: %w", err)
+	}
+	if project.Annotations == nil {
+		project.Annotations = make(map[string]string)
+	}
+	project.Annotations[managedDestinationsAnnotation] = string(value)
+	return nil
+}
+
+func containsDestination(destinations []argocdv1alpha1.ApplicationDestination, server, namespace string) bool {
+	for _, d := range destinations {
+		if d.Server == server && d.Namespace == namespace {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package application
+
+import (
+	"context"
+	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	"github.com/mdvorak/argo-application-operator/pkg/apis"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestProjectDestinationsArePrunedWhenDestinationReverts(t *testing.T) {
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{clientgoscheme.AddToScheme, apis.AddToScheme, argocdv1alpha1.AddToScheme} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cr := &opsv1alpha1.Application{ObjectMeta: metav1.ObjectMeta{Name: "guestbook", Namespace: "foo"}}
+	cr.Spec.Destination = &opsv1alpha1.ApplicationDestination{Namespace: "bar"}
+	server := "https://kubernetes.default.svc"
+	other := argocdv1alpha1.ApplicationDestination{Server: server, Namespace: "shared"}
+	c := fake.NewFakeClientWithScheme(scheme,
+		cr,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo", Labels: map[string]string{tenantLabel: "acme"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "bar", Labels: map[string]string{tenantLabel: "acme"}}},
+		&argocdv1alpha1.AppProject{
+			ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "argocd"},
+			Spec:       argocdv1alpha1.AppProjectSpec{Destinations: []argocdv1alpha1.ApplicationDestination{other}},
+		},
+	)
+	r := &ReconcileApplication{client: c, reader: c, scheme: scheme}
+	b := &argocdBackend{client: c, scheme: scheme}
+	app := &argocdv1alpha1.Application{ObjectMeta: metav1.ObjectMeta{Name: "foo-guestbook", Namespace: "argocd"}}
+	app.Spec.Project = "foo"
+
+	reconcileDestinations := func() []argocdv1alpha1.ApplicationDestination {
+		namespaces, err := r.checkDestination(context.TODO(), cr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := b.updateProjectDestinations(context.TODO(), log, app, server, namespaces); err != nil {
+			t.Fatal(err)
+		}
+		project := &argocdv1alpha1.AppProject{}
+		if err := c.Get(context.TODO(), types.NamespacedName{Name: "foo", Namespace: "argocd"}, project); err != nil {
+			t.Fatal(err)
+		}
+		if _, managed := project.Annotations[managedDestinationsAnnotation]; managed != (len(namespaces) > 0) {
+			t.Errorf("expected managed destinations annotation %v, got %v", len(namespaces) > 0, project.Annotations)
+		}
+		return project.Spec.Destinations
+	}
+
+	// Deploy into other namespace of the tenant
+	expected := []argocdv1alpha1.ApplicationDestination{other, {Server: server, Namespace: "bar"}, {Server: server, Namespace: "foo"}}
+	if actual := reconcileDestinations(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected destinations %v, got %v", expected, actual)
+	}
+
+	// Revert destination to own namespace, tenant destinations are pruned, other one is kept
+	cr.Spec.Destination = nil
+	if err := c.Update(context.TODO(), cr); err != nil {
+		t.Fatal(err)
+	}
+	expected = []argocdv1alpha1.ApplicationDestination{other}
+	if actual := reconcileDestinations(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected destinations %v, got %v", expected, actual)
+	}
+}
@@ -0,0 +1,106 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-logr/logr"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"github.com/operator-framework/operator-sdk/pkg/status"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"time"
+)
+
+var expirationWarning time.Duration
+
+const expiringCondition = "Expiring"
+
+// Annotation with a duration, which is added to the expiration time of the Application.ops.csas.cz
+const ttlExtensionAnnotation = "application.ops.csas.cz/ttl-extension"
+
+// Returns time when given CR expires, or nil if it does not expire at all
+func expirationTime(cr *opsv1alpha1.Application) (*time.Time, error) {
+	var expiresAt *time.Time
+
+	if cr.Spec.TTL != nil {
+		t := cr.CreationTimestamp.Add(cr.Spec.TTL.Duration)
+		expiresAt = &t
+	}
+	if cr.Spec.ExpiresAt != nil && (expiresAt == nil || cr.Spec.ExpiresAt.Time.Before(*expiresAt)) {
+		t := cr.Spec.ExpiresAt.Time
+		expiresAt = &t
+	}
+	if expiresAt == nil {
+		// Never expires
+		return nil, nil
+	}
+
+	// Extension
+	if value := cr.Annotations[ttlExtensionAnnotation]; len(value) > 0 {
+		extension, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, newInvalidSpecError("invalid %s annotation: %w", ttlExtensionAnnotation, err)
+		}
+
+		t := expiresAt.Add(extension)
+		expiresAt = &t
+	}
+
+	return expiresAt, nil
+}
+
+// Deletes the CR when it is expired, otherwise schedules next reconcile to the time it should be warned about or deleted.
+// Returns true when the CR has been deleted.
+func (r *ReconcileApplication) reconcileExpiration(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application) (reconcile.Result, bool, error) {
+	expiresAt, err := expirationTime(cr)
+	if err != nil {
+		return reconcile.Result{}, false, err
+	}
+
+	// Not expiring
+	if expiresAt == nil {
+		r.removeCondition(logger, cr, expiringCondition)
+		return reconcile.Result{}, false, nil
+	}
+
+	now := time.Now()
+	expiresAtMsg := fmt.Sprintf("application expires at %s", expiresAt.UTC().Format(time.RFC3339))
+
+	// Expired
+	if !now.Before(*expiresAt) {
+		logger.Info("Application.ops.csas.cz expired, deleting it", "ExpiresAt", expiresAt)
+		r.recorder.Event(cr, corev1.EventTypeNormal, "Expired", "application expired and is being deleted")
+
+		if err := r.client.Delete(ctx, cr); err != nil && !k8serrors.IsNotFound(err) {
+			return reconcile.Result{}, false, fmt.Errorf("failed to delete expired Application.ops.csas.cz: %w", err)
+		}
+		return reconcile.Result{}, true, nil
+	}
+
+	// Expires soon
+	warnAt := expiresAt.Add(-expirationWarning)
+	if !now.Before(warnAt) {
+		if !cr.Status.Conditions.IsTrueFor(expiringCondition) {
+			msg := fmt.Sprintf("%s, annotate it with %s to extend it", expiresAtMsg, ttlExtensionAnnotation)
+			r.recorder.Event(cr, corev1.EventTypeWarning, "Expiring", msg)
+		}
+
+		r.setCondition(logger, cr, status.Condition{
+			Type:    expiringCondition,
+			Status:  corev1.ConditionTrue,
+			Reason:  "ExpiresSoon",
+			Message: expiresAtMsg,
+		})
+		return reconcile.Result{RequeueAfter: expiresAt.Sub(now)}, false, nil
+	}
+
+	// Scheduled
+	r.setCondition(logger, cr, status.Condition{
+		Type:    expiringCondition,
+		Status:  corev1.ConditionFalse,
+		Reason:  "Scheduled",
+		Message: expiresAtMsg,
+	})
+	return reconcile.Result{RequeueAfter: warnAt.Sub(now)}, false, nil
+}
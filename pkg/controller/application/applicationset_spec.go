@@ -0,0 +1,148 @@
+package application
+
+import (
+	"fmt"
+	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var applicationSetGVK = schema.GroupVersionKind{Group: "argoproj.io", Version: "v1alpha1", Kind: "ApplicationSet"}
+
+// Health statuses ordered from the best to the worst, used for aggregation
+var healthStatusOrder = []argocdv1alpha1.HealthStatusCode{
+	argocdv1alpha1.HealthStatusHealthy,
+	argocdv1alpha1.HealthStatusSuspended,
+	argocdv1alpha1.HealthStatusProgressing,
+	argocdv1alpha1.HealthStatusDegraded,
+	argocdv1alpha1.HealthStatusMissing,
+	argocdv1alpha1.HealthStatusUnknown,
+}
+
+// Returns name of applications generated by the ApplicationSet, with {{name}} being the cluster name
+func fanOutApplicationName(app *argocdv1alpha1.Application) string {
+	return "{{name}}-" + app.Name
+}
+
+// Returns ApplicationSet, which generates given application for each of the CR clusters
+func (b *argocdBackend) newApplicationSet(cr *opsv1alpha1.Application, app *argocdv1alpha1.Application) (*unstructured.Unstructured, error) {
+	clusters := cr.Spec.Clusters
+	if len(clusters.Names) == 0 && clusters.Selector == nil {
+		return nil, newInvalidSpecError("clusters must define either names or selector")
+	}
+
+	// Generators, both provide name of the cluster
+	var generators []interface{}
+	if len(clusters.Names) > 0 {
+		elements := make([]interface{}, 0, len(clusters.Names))
+		for _, name := range clusters.Names {
+			elements = append(elements, map[string]interface{}{"name": name})
+		}
+		generators = append(generators, map[string]interface{}{
+			"list": map[string]interface{}{"elements": elements},
+		})
+	}
+	if clusters.Selector != nil {
+		selector, err := runtime.DefaultUnstructuredConverter.ToUnstructured(clusters.Selector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert cluster selector: %w", err)
+		}
+		generators = append(generators, map[string]interface{}{
+			"clusters": map[string]interface{}{"selector": selector},
+		})
+	}
+
+	// Template
+	spec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&app.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert application spec: %w", err)
+	}
+	spec["destination"] = map[string]interface{}{
+		"name":      "{{name}}",
+		"namespace": cr.DestinationNamespace(),
+	}
+
+	labels := make(map[string]interface{}, len(app.Labels))
+	for label, value := range app.Labels {
+		labels[label] = value
+	}
+	annotations := make(map[string]interface{}, len(app.Annotations))
+	for annotation, value := range app.Annotations {
+		annotations[annotation] = value
+	}
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"generators": generators,
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"name":        fanOutApplicationName(app),
+					"labels":      labels,
+					"annotations": annotations,
+				},
+				"spec": spec,
+			},
+		},
+	}}
+	obj.SetGroupVersionKind(applicationSetGVK)
+	obj.SetName(app.Name)
+	obj.SetNamespace(app.Namespace)
+	// Propagated metadata is set on the generated applications only
+	obj.SetLabels(b.applicationLabels(cr))
+	return obj, nil
+}
+
+// Returns aggregated sync and health status of generated applications.
+// Application is synced only when all of them are, and its health is the worst one.
+func aggregateStatus(apps []argocdv1alpha1.Application) (argocdv1alpha1.SyncStatusCode, argocdv1alpha1.HealthStatusCode) {
+	if len(apps) == 0 {
+		return argocdv1alpha1.SyncStatusCodeUnknown, argocdv1alpha1.HealthStatusMissing
+	}
+
+	syncStatus := argocdv1alpha1.SyncStatusCodeSynced
+	healthStatus := argocdv1alpha1.HealthStatusHealthy
+	for _, app := range apps {
+		switch app.Status.Sync.Status {
+		case argocdv1alpha1.SyncStatusCodeSynced:
+		case argocdv1alpha1.SyncStatusCodeOutOfSync:
+			syncStatus = argocdv1alpha1.SyncStatusCodeOutOfSync
+		default:
+			if syncStatus == argocdv1alpha1.SyncStatusCodeSynced {
+				syncStatus = argocdv1alpha1.SyncStatusCodeUnknown
+			}
+		}
+
+		if rank := healthRank(app.Status.Health.Status); rank > healthRank(healthStatus) {
+			healthStatus = healthStatusOrder[rank]
+		}
+	}
+
+	return syncStatus, healthStatus
+}
+
+// Returns revision synced to all generated applications, or empty string when they differ
+func aggregateRevision(apps []argocdv1alpha1.Application) string {
+	if len(apps) == 0 {
+		return ""
+	}
+
+	revision := apps[0].Status.Sync.Revision
+	for _, app := range apps[1:] {
+		if app.Status.Sync.Revision != revision {
+			return ""
+		}
+	}
+	return revision
+}
+
+func healthRank(health argocdv1alpha1.HealthStatusCode) int {
+	for i, h := range healthStatusOrder {
+		if h == health {
+			return i
+		}
+	}
+	// Empty or unknown status
+	return len(healthStatusOrder) - 1
+}
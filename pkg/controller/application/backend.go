@@ -0,0 +1,77 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	"github.com/go-logr/logr"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"github.com/mdvorak/argo-application-operator/pkg/delivery"
+	"github.com/operator-framework/operator-sdk/pkg/status"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Delivery backend, which generates and manages target objects of Application.ops.csas.cz
+type backend interface {
+	// Registers watches of target objects, which requeue their owner
+	watch(c controller.Controller) error
+	// Creates or updates target objects of the CR, and returns their observed state
+	reconcile(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application, opts targetOptions) (reconcile.Result, targetState, error)
+	// Deletes all target objects of the CR
+	finalize(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application) error
+	// Requests target objects of the CR to fetch their source again, e.g. after a git push
+	refresh(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application) error
+}
+
+// Options of target objects, which are not part of the CR spec
+type targetOptions struct {
+	// Disables automated sync of the target, even if it is requested by the CR
+	disableAutomatedSync bool
+	// Namespaces of the tenant, which applications of the namespace may deploy into, empty when all of them deploy
+	// into their own namespace
+	tenantNamespaces []string
+	// Labels and annotations propagated from the CR and its namespace
+	labels      map[string]string
+	annotations map[string]string
+	// How drift of target objects from the desired state is handled
+	driftPolicy string
+}
+
+// Observed state of target objects
+type targetState struct {
+	// References to existing target objects
+	references []opsv1alpha1.Reference
+	// True when sync and health status has been observed
+	observed     bool
+	syncStatus   argocdv1alpha1.SyncStatusCode
+	healthStatus argocdv1alpha1.HealthStatusCode
+	// Revision synced to target objects, empty when unknown
+	syncedRevision string
+	// Paused condition, nil when not paused
+	paused *status.Condition
+	// Conflict condition, nil when target objects were applied without conflicts
+	conflict *status.Condition
+	// Fields of target objects, which drifted from the desired state
+	drift []string
+	// State of sync windows, nil when there are none
+	syncWindow *opsv1alpha1.SyncWindowStatus
+}
+
+// Creates backend selected by the configuration
+func newBackend(mgr manager.Manager) (backend, error) {
+	name, err := delivery.GetBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case delivery.BackendArgoCD:
+		return newArgoCDBackend(mgr)
+	case delivery.BackendFlux:
+		return newFluxBackend(mgr)
+	default:
+		return nil, fmt.Errorf("unsupported delivery backend %s", name)
+	}
+}
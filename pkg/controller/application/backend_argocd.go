@@ -0,0 +1,614 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	"github.com/go-logr/logr"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"github.com/mdvorak/argo-application-operator/pkg/argocd"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"strings"
+)
+
+// Annotation requesting Argo CD to refresh the application
+const refreshAnnotation = "argocd.argoproj.io/refresh"
+
+// blank assignment to verify that argocdBackend implements backend
+var _ backend = &argocdBackend{}
+
+// Backend generating Application.argocd.io objects
+type argocdBackend struct {
+	client client.Client
+	scheme *runtime.Scheme
+	// Reader of source namespaces, from the cache of the local cluster
+	namespaceReader client.Reader
+	// Argo CD instances, first one being the default
+	instances       []argocd.Instance
+	applicationSets bool
+	// Cache of the remote cluster in hub mode, nil when Argo CD runs in the local cluster
+	cache cache.Cache
+	// Name of the local cluster registered in remote Argo CD, empty when Argo CD runs in the local cluster
+	clusterName string
+}
+
+func newArgoCDBackend(mgr manager.Manager) (*argocdBackend, error) {
+	instances, err := argocd.GetInstances()
+	if err != nil {
+		return nil, fmt.Errorf("argo instances must be set: %w", err)
+	}
+	applicationSets, err := argocd.GetApplicationSetsEnabled()
+	if err != nil {
+		return nil, err
+	}
+
+	b := &argocdBackend{
+		client:          mgr.GetClient(),
+		scheme:          mgr.GetScheme(),
+		namespaceReader: mgr.GetClient(),
+		instances:       instances,
+		applicationSets: applicationSets,
+	}
+
+	// Hub mode
+	secret, err := argocd.GetKubeconfigSecret()
+	if err != nil {
+		return nil, err
+	}
+	if secret != nil {
+		if err := b.connectRemote(mgr, *secret); err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// Switches the backend to Argo CD running in a remote cluster, with destination being the local cluster
+func (b *argocdBackend) connectRemote(mgr manager.Manager, secret types.NamespacedName) error {
+	clusterName, err := argocd.GetClusterName()
+	if err != nil {
+		return fmt.Errorf("cluster name must be set in hub mode: %w", err)
+	}
+
+	ctx := context.Background()
+	remote, err := argocd.NewRemoteCluster(ctx, mgr.GetAPIReader(), secret, mgr.GetScheme(), argocd.InstanceNamespaces(b.instances))
+	if err != nil {
+		return err
+	}
+
+	// Each instance has the cluster registered separately, unless its server is configured explicitly
+	server := argocd.GetClusterServer()
+	for i := range b.instances {
+		if len(server) > 0 {
+			b.instances[i].DestinationServer = server
+			continue
+		}
+
+		found, err := remote.ClusterServer(ctx, b.instances[i].Namespace, clusterName)
+		if err != nil {
+			return fmt.Errorf("argo instance %s: %w", b.instances[i].Name, err)
+		}
+		b.instances[i].DestinationServer = found
+	}
+
+	// Remote cache is started together with the controllers
+	if err := mgr.Add(remote.Cache); err != nil {
+		return fmt.Errorf("failed to add remote cache to manager: %w", err)
+	}
+
+	b.client = remote.Client
+	b.cache = remote.Cache
+	b.clusterName = clusterName
+	return nil
+}
+
+// Returns Argo CD instance selected by the label of the CR namespace, or the default one
+func (b *argocdBackend) instanceFor(ctx context.Context, cr *opsv1alpha1.Application) (*argocd.Instance, error) {
+	if len(b.instances) == 1 {
+		return &b.instances[0], nil
+	}
+
+	namespace := &corev1.Namespace{}
+	if err := b.namespaceReader.Get(ctx, types.NamespacedName{Name: cr.Namespace}, namespace); err != nil {
+		return nil, fmt.Errorf("failed to get namespace %s: %w", cr.Namespace, err)
+	}
+
+	name, ok := namespace.Labels[argocd.InstanceLabel]
+	if !ok {
+		return &b.instances[0], nil
+	}
+	for i := range b.instances {
+		if b.instances[i].Name == name {
+			return &b.instances[i], nil
+		}
+	}
+	return nil, newPolicyViolationError("argo instance %s selected by namespace %s does not exist", name, cr.Namespace)
+}
+
+// Returns other instances, which the CR references generated objects in, that is, where it was deployed before
+func (b *argocdBackend) previousInstances(cr *opsv1alpha1.Application, instance *argocd.Instance) []*argocd.Instance {
+	var previous []*argocd.Instance
+	for _, ref := range cr.Status.References {
+		if !strings.HasPrefix(ref.APIVersion, argocdv1alpha1.SchemeGroupVersion.Group+"/") || ref.Namespace == instance.Namespace {
+			continue
+		}
+		for i := range b.instances {
+			if b.instances[i].Namespace == ref.Namespace && !containsInstance(previous, &b.instances[i]) {
+				previous = append(previous, &b.instances[i])
+			}
+		}
+	}
+	return previous
+}
+
+func containsInstance(instances []*argocd.Instance, instance *argocd.Instance) bool {
+	for _, i := range instances {
+		if i == instance {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns name of target objects, prefixed also with the cluster name in hub mode, to avoid conflicts between clusters
+func (b *argocdBackend) targetName(cr *opsv1alpha1.Application) string {
+	if len(b.clusterName) > 0 {
+		return b.clusterName + "-" + targetName(cr)
+	}
+	return targetName(cr)
+}
+
+// Returns labels of target objects, including the cluster name in hub mode
+func (b *argocdBackend) applicationLabels(cr *opsv1alpha1.Application) map[string]string {
+	labels := applicationLabels(cr)
+	if len(b.clusterName) > 0 {
+		labels[ownerClusterLabel] = b.clusterName
+	}
+	return labels
+}
+
+// Same as isApplicationOwnedBy, but checks also the cluster in hub mode
+func (b *argocdBackend) isApplicationOwnedBy(obj metav1.Object, cr *opsv1alpha1.Application) bool {
+	return isApplicationOwnedBy(obj, cr) || obj.GetLabels()[ownerClusterLabel] != b.clusterName
+}
+
+// Returns source of target objects, from the remote cluster in hub mode
+func (b *argocdBackend) source(obj runtime.Object) source.Source {
+	src := &source.Kind{Type: obj}
+	if b.cache != nil {
+		// Kind keeps the cache, when it is set before the manager injects its own
+		_ = src.InjectCache(b.cache)
+	}
+	return src
+}
+
+func (b *argocdBackend) watch(c controller.Controller) error {
+	// Watch for changes to secondary resource Application and requeue the owner Application
+	err := c.Watch(b.source(&argocdv1alpha1.Application{}), &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(watchMapFunc),
+	}, argocd.ApplicationUpdatedPredicate{})
+	if err != nil {
+		return fmt.Errorf("failed to watch target objects: %w", err)
+	}
+
+	// Watch for changes to ApplicationSet, only when its CRD is installed
+	if b.applicationSets {
+		appSet := &unstructured.Unstructured{}
+		appSet.SetGroupVersionKind(applicationSetGVK)
+
+		err = c.Watch(b.source(appSet), &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(watchMapFunc),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to watch target objects %s.%s: %w", applicationSetGVK.Kind, applicationSetGVK.Group, err)
+		}
+	}
+
+	return nil
+}
+
+func (b *argocdBackend) reconcile(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application, opts targetOptions) (reconcile.Result, targetState, error) {
+	instance, err := b.instanceFor(ctx, cr)
+	if err != nil {
+		return reconcile.Result{}, targetState{}, err
+	}
+
+	// Remove objects from previous instances, when the instance has changed
+	for _, previous := range b.previousInstances(cr, instance) {
+		if err := b.finalizeInstance(ctx, logger, cr, previous); err != nil {
+			return reconcile.Result{}, targetState{}, err
+		}
+	}
+
+	// Define a new Argo Application object
+	app := b.newApplication(cr, instance)
+	setPropagatedMetadata(app, opts.labels, opts.annotations)
+	if opts.disableAutomatedSync {
+		app.Spec.SyncPolicy = withoutAutomatedSync(app.Spec.SyncPolicy)
+	}
+	logger = logger.WithValues("Application.Namespace", app.Namespace, "Application.Name", app.Name)
+
+	// Allow deployment into other namespaces of the tenant, or prune them when no longer needed
+	server := app.Spec.Destination.Server
+	if cr.Spec.Clusters != nil {
+		// Any of the generated clusters
+		server = "*"
+	}
+	if err := b.updateProjectDestinations(ctx, logger, app, server, opts.tenantNamespaces); err != nil {
+		return reconcile.Result{}, targetState{}, err
+	}
+
+	// Deploy to multiple clusters
+	if cr.Spec.Clusters != nil {
+		return b.reconcileFanOut(ctx, logger, cr, app)
+	}
+
+	// Remove ApplicationSet and its sync windows, when clusters have been removed from the CR
+	if err := b.deleteApplicationSet(ctx, logger, cr, app); err != nil {
+		return reconcile.Result{}, targetState{}, err
+	}
+
+	// Update application
+	state, err := b.reconcileUpdate(ctx, logger, cr, app, opts)
+	if err != nil {
+		return reconcile.Result{}, state, err
+	}
+
+	// Render sync windows into the project
+	result, windowStatus, err := b.reconcileSyncWindows(ctx, logger, cr, app)
+	state.syncWindow = windowStatus
+	return result, state, err
+}
+
+// Generates ApplicationSet instead of the single application
+func (b *argocdBackend) reconcileFanOut(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application, app *argocdv1alpha1.Application) (reconcile.Result, targetState, error) {
+	if !b.applicationSets {
+		return reconcile.Result{}, targetState{}, newPolicyViolationError("clusters are not supported, ApplicationSets are disabled by %s", argocd.ApplicationSetsEnvVar)
+	}
+
+	appSet, err := b.newApplicationSet(cr, app)
+	if err != nil {
+		return reconcile.Result{}, targetState{}, err
+	}
+
+	// Remove single application and its sync windows, when clusters have been added to the CR
+	if err := b.deleteApplication(ctx, logger, cr, app); err != nil {
+		return reconcile.Result{}, targetState{}, err
+	}
+
+	// Update ApplicationSet
+	state, err := b.reconcileApplicationSet(ctx, logger, cr, appSet)
+	if err != nil {
+		return reconcile.Result{}, state, err
+	}
+
+	// Render sync windows into the project, applying to all generated applications
+	result, windowStatus, err := b.reconcileSyncWindows(ctx, logger, cr, fanOutSyncWindowsTarget(app))
+	state.syncWindow = windowStatus
+	return result, state, err
+}
+
+func (b *argocdBackend) reconcileApplicationSet(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application, appSet *unstructured.Unstructured) (targetState, error) {
+	// Check if this ApplicationSet already exists
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(applicationSetGVK)
+	err := b.client.Get(ctx, types.NamespacedName{Name: appSet.GetName(), Namespace: appSet.GetNamespace()}, found)
+	if err != nil && k8serrors.IsNotFound(err) {
+		// Don't create anything while paused
+		if cond := newPausedCondition(cr, nil, ""); cond != nil {
+			logger.Info("updates are paused, not creating ApplicationSet.argocd.io")
+			return targetState{paused: cond}, nil
+		}
+
+		logger.Info("creating a new ApplicationSet.argocd.io")
+		state := targetState{}
+		if state.conflict, err = applyObject(ctx, b.client, appSet, false); err != nil || state.conflict != nil {
+			return state, err
+		}
+
+		// ApplicationSet created successfully
+		ref, err := opsv1alpha1.ReferenceFromObject(appSet, appSet, b.scheme)
+		if err != nil {
+			return state, fmt.Errorf("failed build Reference from ApplicationSet object: %w", err)
+		}
+		state.references = []opsv1alpha1.Reference{ref}
+		return state, nil
+	} else if err != nil {
+		return targetState{}, fmt.Errorf("failed to get existing ApplicationSet.argocd.io: %w", err)
+	}
+
+	// Verify ownership
+	if b.isApplicationOwnedBy(found, cr) {
+		// Not owned by this CR! This will fail repeatedly, but its ok - should not happen in real-life
+		return targetState{}, newNameConflictError("object %s.%s \"%s\" in namespace \"%s\" already exists, and it is not owned by this object", found.GetKind(), applicationSetGVK.Group, found.GetName(), found.GetNamespace())
+	}
+
+	state, err := b.newFanOutTargetState(ctx, cr, found)
+	if err != nil {
+		return state, err
+	}
+
+	// Don't revert manual changes while paused
+	if state.paused = newPausedCondition(cr, found, "ApplicationSet.argocd.io"); state.paused != nil {
+		logger.Info("updates are paused, not updating ApplicationSet.argocd.io")
+		return state, nil
+	}
+
+	// ApplicationSet exists, apply owned fields, fields no longer set are removed
+	if state.conflict, err = applyObject(ctx, b.client, appSet, false); err != nil {
+		return state, err
+	}
+	if appSet.GetResourceVersion() != found.GetResourceVersion() {
+		logger.Info("updated existing ApplicationSet.argocd.io")
+	}
+
+	return state, nil
+}
+
+// Returns state with references to the ApplicationSet and all its generated applications, and their aggregated status
+func (b *argocdBackend) newFanOutTargetState(ctx context.Context, cr *opsv1alpha1.Application, appSet *unstructured.Unstructured) (targetState, error) {
+	ref, err := opsv1alpha1.ReferenceFromObject(appSet, appSet, b.scheme)
+	if err != nil {
+		return targetState{}, fmt.Errorf("failed build Reference from ApplicationSet object: %w", err)
+	}
+	state := targetState{references: []opsv1alpha1.Reference{ref}, observed: true}
+
+	// Generated applications carry the same labels
+	apps := &argocdv1alpha1.ApplicationList{}
+	if err := b.client.List(ctx, apps, client.InNamespace(appSet.GetNamespace()), client.MatchingLabels(b.applicationLabels(cr))); err != nil {
+		return state, fmt.Errorf("failed to list generated Application.argocd.io: %w", err)
+	}
+
+	for i := range apps.Items {
+		ref, err := opsv1alpha1.ReferenceFromApplication(&apps.Items[i], b.scheme)
+		if err != nil {
+			return state, fmt.Errorf("failed build Reference from app object: %w", err)
+		}
+		state.references = append(state.references, ref)
+	}
+
+	state.syncStatus, state.healthStatus = aggregateStatus(apps.Items)
+	state.syncedRevision = aggregateRevision(apps.Items)
+	return state, nil
+}
+
+// Returns application, whose sync windows apply to all applications generated by its ApplicationSet
+func fanOutSyncWindowsTarget(app *argocdv1alpha1.Application) *argocdv1alpha1.Application {
+	target := app.DeepCopy()
+	target.Name = "*-" + app.Name
+	return target
+}
+
+func (b *argocdBackend) reconcileUpdate(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application, app *argocdv1alpha1.Application, opts targetOptions) (targetState, error) {
+	applied, err := newApplyObject(app, argocdv1alpha1.SchemeGroupVersion.WithKind("Application"))
+	if err != nil {
+		return targetState{}, err
+	}
+	if err := setLastApplied(applied); err != nil {
+		return targetState{}, err
+	}
+
+	// Check if this Application already exists
+	found := &argocdv1alpha1.Application{}
+	err = b.client.Get(ctx, types.NamespacedName{Name: app.Name, Namespace: app.Namespace}, found)
+	if err != nil && k8serrors.IsNotFound(err) {
+		// Don't create anything while paused
+		if cond := newPausedCondition(cr, nil, ""); cond != nil {
+			logger.Info("updates are paused, not creating Application.argocd.io")
+			return targetState{paused: cond}, nil
+		}
+
+		logger.Info("creating a new Application.argocd.io")
+		conflict, err := applyObject(ctx, b.client, applied, false)
+		if err != nil || conflict != nil {
+			return targetState{conflict: conflict}, err
+		}
+
+		// Application created successfully
+		return b.newTargetState(app)
+	} else if err != nil {
+		return targetState{}, fmt.Errorf("failed to get existing Application.argocd.io: %w", err)
+	}
+
+	// Verify ownership
+	if b.isApplicationOwnedBy(found, cr) {
+		// Not owned by this CR! This will fail repeatedly, but its ok - should not happen in real-life
+		return targetState{}, newNameConflictError("object %s.%s \"%s\" in namespace \"%s\" already exists, and it is not owned by this object", found.Kind, found.GroupVersionKind().Group, found.Name, found.Namespace)
+	}
+
+	state, err := b.newTargetState(found)
+	if err != nil {
+		return state, err
+	}
+	state.observed = true
+
+	// Don't revert manual changes while paused
+	if state.paused = newPausedCondition(cr, found, "Application.argocd.io"); state.paused != nil {
+		logger.Info("updates are paused, not updating Application.argocd.io")
+		return state, nil
+	}
+
+	// Detect manual changes of the fields last applied by the operator, changes of the CR are not a drift
+	live, err := newApplyObject(found, applied.GroupVersionKind())
+	if err != nil {
+		return state, err
+	}
+	if state.drift, err = detectDrift(live); err != nil {
+		return state, err
+	}
+	if len(state.drift) > 0 && opts.driftPolicy == driftPolicyReport {
+		// Apply only changes of the CR, drifted fields are kept
+		if applied, err = keepDrift(applied, live); err != nil {
+			return state, err
+		} else if applied == nil {
+			logger.Info("Application.argocd.io drifted, not updating it", "Drift", state.drift)
+			return state, nil
+		}
+	}
+
+	// Application exists, apply owned fields, fields no longer set are removed
+	force := len(state.drift) > 0 && opts.driftPolicy == driftPolicyRevert
+	if state.conflict, err = applyObject(ctx, b.client, applied, force); err != nil {
+		return state, err
+	}
+	if applied.GetResourceVersion() != found.ResourceVersion {
+		logger.Info("updated existing Application.argocd.io")
+	}
+
+	// Application already exists
+	return state, nil
+}
+
+func (b *argocdBackend) newTargetState(app *argocdv1alpha1.Application) (targetState, error) {
+	ref, err := opsv1alpha1.ReferenceFromApplication(app, b.scheme)
+	if err != nil {
+		return targetState{}, fmt.Errorf("failed build Reference from app object: %w", err)
+	}
+
+	return targetState{
+		references:     []opsv1alpha1.Reference{ref},
+		syncStatus:     app.Status.Sync.Status,
+		healthStatus:   app.Status.Health.Status,
+		syncedRevision: app.Status.Sync.Revision,
+	}, nil
+}
+
+func (b *argocdBackend) finalize(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application) error {
+	for i := range b.instances {
+		if err := b.finalizeInstance(ctx, logger, cr, &b.instances[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deletes all target objects of the CR in given instance
+func (b *argocdBackend) finalizeInstance(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application, instance *argocd.Instance) error {
+	app := b.newApplication(cr, instance)
+	logger = logger.WithValues("Application.Namespace", app.Namespace, "Application.Name", app.Name)
+
+	if err := b.deleteApplicationSet(ctx, logger, cr, app); err != nil {
+		return err
+	}
+	return b.deleteApplication(ctx, logger, cr, app)
+}
+
+// Deletes the single application and its sync windows, if it exists and is owned by the CR
+func (b *argocdBackend) deleteApplication(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application, app *argocdv1alpha1.Application) error {
+	// Remove sync windows from the project
+	if err := b.updateProjectSyncWindows(ctx, logger, app, nil); err != nil {
+		return err
+	}
+
+	// Check if this Application exists
+	found := &argocdv1alpha1.Application{}
+	err := b.client.Get(ctx, types.NamespacedName{Name: app.Name, Namespace: app.Namespace}, found)
+	if err != nil {
+		// If there was error but it wasn't NotFound, propagate the error
+		if k8serrors.IsNotFound(err) {
+			// Already deleted, nothing to do
+			return nil
+		}
+
+		return fmt.Errorf("failed to get Application.argocd.io for deletion: %w", err)
+	}
+
+	if b.isApplicationOwnedBy(found, cr) {
+		// Not ours, leave it alone
+		return nil
+	}
+
+	// Delete
+	logger.Info("deleting Application.argocd.io")
+	err = b.client.Delete(ctx, found)
+	if err != nil {
+		return fmt.Errorf("failed to delete Application.argocd.io: %w", err)
+	}
+
+	return nil
+}
+
+// Deletes the ApplicationSet and its sync windows, if it exists and is owned by the CR.
+// Generated applications are deleted by the ApplicationSet controller.
+func (b *argocdBackend) deleteApplicationSet(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application, app *argocdv1alpha1.Application) error {
+	if !b.applicationSets {
+		// Cannot exist
+		return nil
+	}
+
+	// Remove sync windows from the project
+	if err := b.updateProjectSyncWindows(ctx, logger, fanOutSyncWindowsTarget(app), nil); err != nil {
+		return err
+	}
+
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(applicationSetGVK)
+	err := b.client.Get(ctx, types.NamespacedName{Name: app.Name, Namespace: app.Namespace}, found)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			// Nothing to delete
+			return nil
+		}
+
+		return fmt.Errorf("failed to get ApplicationSet.argocd.io for deletion: %w", err)
+	}
+
+	if b.isApplicationOwnedBy(found, cr) {
+		// Not ours, leave it alone
+		return nil
+	}
+
+	logger.Info("deleting ApplicationSet.argocd.io")
+	if err := b.client.Delete(ctx, found); err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete ApplicationSet.argocd.io: %w", err)
+	}
+
+	return nil
+}
+
+// Requests refresh of all applications referenced by the CR, including ones generated by its ApplicationSet
+func (b *argocdBackend) refresh(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application) error {
+	for _, ref := range cr.Status.References {
+		if ref.APIVersion != argocdv1alpha1.SchemeGroupVersion.String() || ref.Kind != "Application" {
+			continue
+		}
+
+		found := &argocdv1alpha1.Application{}
+		err := b.client.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: ref.Namespace}, found)
+		if err != nil {
+			if k8serrors.IsNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get Application.argocd.io for refresh: %w", err)
+		}
+		if b.isApplicationOwnedBy(found, cr) {
+			continue
+		}
+
+		// Copy instance for patch
+		newApp := found.DeepCopy()
+		if newApp.Annotations == nil {
+			newApp.Annotations = make(map[string]string)
+		}
+		newApp.Annotations[refreshAnnotation] = string(argocdv1alpha1.RefreshTypeNormal)
+
+		logger.Info("refreshing Application.argocd.io", "Application.Namespace", found.Namespace, "Application.Name", found.Name)
+		if err := b.client.Patch(ctx, newApp, client.MergeFrom(found)); err != nil {
+			return fmt.Errorf("failed to refresh Application.argocd.io: %w", err)
+		}
+	}
+	return nil
+}
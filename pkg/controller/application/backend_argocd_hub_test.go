@@ -0,0 +1,214 @@
+package application
+
+import (
+	"context"
+	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	"github.com/mdvorak/argo-application-operator/pkg/apis"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"github.com/mdvorak/argo-application-operator/pkg/argocd"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"os"
+	"path/filepath"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"testing"
+	"time"
+)
+
+const hubTestTimeout = 30 * time.Second
+
+// Runs the operator in hub mode against two API servers, the workload cluster with Application.ops.csas.cz,
+// and the management cluster with Argo CD. Requires envtest binaries, see KUBEBUILDER_ASSETS.
+func TestHubMode(t *testing.T) {
+	if !hasEnvtestAssets() {
+		t.Skip("envtest binaries not available, set KUBEBUILDER_ASSETS")
+	}
+
+	scheme := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{clientgoscheme.AddToScheme, apis.AddToScheme, argocdv1alpha1.AddToScheme} {
+		if err := add(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Clusters
+	localEnv := &envtest.Environment{CRDDirectoryPaths: []string{filepath.Join("..", "..", "..", "deploy", "crds")}}
+	localCfg, err := localEnv.Start()
+	if err != nil {
+		t.Fatalf("failed to start workload cluster: %v", err)
+	}
+	defer func() { _ = localEnv.Stop() }()
+
+	remoteEnv := &envtest.Environment{CRDDirectoryPaths: []string{"testdata"}}
+	remoteCfg, err := remoteEnv.Start()
+	if err != nil {
+		t.Fatalf("failed to start management cluster: %v", err)
+	}
+	defer func() { _ = remoteEnv.Stop() }()
+
+	local, err := client.New(localCfg, client.Options{Scheme: scheme})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote, err := client.New(remoteCfg, client.Options{Scheme: scheme})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Setup
+	ctx := context.Background()
+	kubeconfig, err := kubeconfigFor(remoteCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustCreate(t, local, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "operator"}})
+	mustCreate(t, local, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "foo"}})
+	mustCreate(t, local, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "argo-kubeconfig", Namespace: "operator"},
+		Data:       map[string][]byte{argocd.KubeconfigSecretKey: kubeconfig},
+	})
+	mustCreate(t, remote, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "argocd"}})
+	mustCreate(t, remote, &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "cluster-workload",
+			Namespace: "argocd",
+			Labels:    map[string]string{argocd.ClusterSecretTypeLabel: argocd.ClusterSecretTypeCluster},
+		},
+		Data: map[string][]byte{"name": []byte("workload"), "server": []byte("https://workload.example.com")},
+	})
+
+	defer setEnv(t, argocd.NamespaceEnvVar, "argocd")()
+	defer setEnv(t, argocd.KubeconfigSecretEnvVar, "operator/argo-kubeconfig")()
+	defer setEnv(t, argocd.ClusterNameEnvVar, "workload")()
+
+	// Operator
+	mgr, err := manager.New(localCfg, manager.Options{Scheme: scheme, MetricsBindAddress: "0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Add(mgr); err != nil {
+		t.Fatalf("failed to add controller: %v", err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		if err := mgr.Start(stop); err != nil {
+			t.Errorf("manager failed: %v", err)
+		}
+	}()
+
+	// Create
+	cr := &opsv1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{Name: "guestbook", Namespace: "foo"},
+		Spec: opsv1alpha1.ApplicationSpec{
+			Source: argocdv1alpha1.ApplicationSource{
+				RepoURL:        "https://github.com/argoproj/argocd-example-apps",
+				Path:           "guestbook",
+				TargetRevision: "HEAD",
+			},
+		},
+	}
+	mustCreate(t, local, cr)
+
+	app := &argocdv1alpha1.Application{}
+	appName := types.NamespacedName{Name: "workload-foo-guestbook", Namespace: "argocd"}
+	waitFor(t, "generated application in management cluster", func() (bool, error) {
+		return get(ctx, remote, appName, app)
+	})
+	if app.Spec.Destination.Server != "https://workload.example.com" {
+		t.Errorf("expected destination of the workload cluster, got %s", app.Spec.Destination.Server)
+	}
+	if app.Labels[ownerClusterLabel] != "workload" {
+		t.Errorf("expected %s label, got %v", ownerClusterLabel, app.Labels)
+	}
+
+	// Status mirroring
+	app.Status.Sync.Status = argocdv1alpha1.SyncStatusCodeSynced
+	app.Status.Sync.Revision = "53e28ff20cc530b9ada2173fbbd64d48338583ba"
+	app.Status.Health.Status = argocdv1alpha1.HealthStatusHealthy
+	if err := remote.Update(ctx, app); err != nil {
+		t.Fatalf("failed to update status of generated application: %v", err)
+	}
+
+	waitFor(t, "mirrored status", func() (bool, error) {
+		if ok, err := get(ctx, local, types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace}, cr); !ok || err != nil {
+			return ok, err
+		}
+		return cr.Status.SyncStatus == argocdv1alpha1.SyncStatusCodeSynced &&
+			cr.Status.HealthStatus == argocdv1alpha1.HealthStatusHealthy &&
+			cr.Status.SyncedRevision == app.Status.Sync.Revision, nil
+	})
+	if !contains(cr.Finalizers, applicationFinalizer) {
+		t.Errorf("expected finalizer %s, got %v", applicationFinalizer, cr.Finalizers)
+	}
+
+	// Finalization
+	if err := local.Delete(ctx, cr); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "deletion of generated application", func() (bool, error) {
+		ok, err := get(ctx, remote, appName, &argocdv1alpha1.Application{})
+		return !ok, err
+	})
+	waitFor(t, "removal of finalizer", func() (bool, error) {
+		ok, err := get(ctx, local, types.NamespacedName{Name: cr.Name, Namespace: cr.Namespace}, &opsv1alpha1.Application{})
+		return !ok, err
+	})
+}
+
+func hasEnvtestAssets() bool {
+	if os.Getenv("KUBEBUILDER_ASSETS") != "" || os.Getenv("TEST_ASSET_KUBE_APISERVER") != "" {
+		return true
+	}
+	_, err := os.Stat("/usr/local/kubebuilder/bin/kube-apiserver")
+	return err == nil
+}
+
+// Returns kubeconfig with the credentials of given config
+func kubeconfigFor(cfg *rest.Config) ([]byte, error) {
+	kc := clientcmdapi.NewConfig()
+	kc.Clusters["remote"] = &clientcmdapi.Cluster{Server: cfg.Host, CertificateAuthorityData: cfg.CAData}
+	kc.AuthInfos["remote"] = &clientcmdapi.AuthInfo{Token: cfg.BearerToken, ClientCertificateData: cfg.CertData, ClientKeyData: cfg.KeyData}
+	kc.Contexts["remote"] = &clientcmdapi.Context{Cluster: "remote", AuthInfo: "remote"}
+	kc.CurrentContext = "remote"
+	return clientcmd.Write(*kc)
+}
+
+// Sets env var, returns function which unsets it
+func setEnv(t *testing.T, key, value string) func() {
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	return func() { _ = os.Unsetenv(key) }
+}
+
+func mustCreate(t *testing.T, c client.Client, obj runtime.Object) {
+	if err := c.Create(context.Background(), obj); err != nil {
+		t.Fatalf("failed to create %T: %v", obj, err)
+	}
+}
+
+// Returns false when the object does not exist
+func get(ctx context.Context, c client.Client, name types.NamespacedName, obj runtime.Object) (bool, error) {
+	err := c.Get(ctx, name, obj)
+	if k8serrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func waitFor(t *testing.T, what string, condition wait.ConditionFunc) {
+	if err := wait.PollImmediate(100*time.Millisecond, hubTestTimeout, condition); err != nil {
+		t.Fatalf("waiting for %s: %v", what, err)
+	}
+}
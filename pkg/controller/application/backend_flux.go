@@ -0,0 +1,281 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-logr/logr"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"github.com/mdvorak/argo-application-operator/pkg/flux"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"time"
+)
+
+// Annotation requesting Flux to reconcile the object immediately
+const reconcileRequestAnnotation = "reconcile.fluxcd.io/requestedAt"
+
+// Namespace annotation, naming the service account in the Flux namespace, which Flux impersonates when deploying
+// applications of the namespace. It is set by cluster admins, as Flux controllers are usually cluster-admins.
+const fluxServiceAccountAnnotation = "application.ops.csas.cz/flux-service-account"
+
+// blank assignment to verify that fluxBackend implements backend
+var _ backend = &fluxBackend{}
+
+// Backend generating Flux GitRepository or HelmRepository, and Kustomization or HelmRelease objects
+type fluxBackend struct {
+	client    client.Client
+	scheme    *runtime.Scheme
+	namespace string
+	interval  time.Duration
+}
+
+func newFluxBackend(mgr manager.Manager) (*fluxBackend, error) {
+	namespace, err := flux.GetNamespace()
+	if err != nil {
+		return nil, fmt.Errorf("flux namespace must be set: %w", err)
+	}
+	interval, err := flux.GetInterval()
+	if err != nil {
+		return nil, err
+	}
+
+	return &fluxBackend{
+		client:    mgr.GetClient(),
+		scheme:    mgr.GetScheme(),
+		namespace: namespace,
+		interval:  interval,
+	}, nil
+}
+
+func (b *fluxBackend) watch(c controller.Controller) error {
+	// Watch for changes to all Flux objects and requeue the owner Application
+	for _, gvk := range fluxGVKs {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+
+		err := c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(watchMapFunc),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to watch target objects %s.%s: %w", gvk.Kind, gvk.Group, err)
+		}
+	}
+
+	return nil
+}
+
+func (b *fluxBackend) reconcile(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application, opts targetOptions) (reconcile.Result, targetState, error) {
+	source, deployer, err := b.newFluxObjects(cr, opts)
+	if err != nil {
+		return reconcile.Result{}, targetState{}, err
+	}
+	setPropagatedMetadata(source, opts.labels, opts.annotations)
+	setPropagatedMetadata(deployer, opts.labels, opts.annotations)
+
+	// Never deploy with privileges of Flux itself
+	serviceAccount, err := b.serviceAccountFor(ctx, cr.Namespace)
+	if err != nil {
+		return reconcile.Result{}, targetState{}, err
+	}
+	if err := unstructured.SetNestedField(deployer.Object, serviceAccount, "spec", "serviceAccountName"); err != nil {
+		return reconcile.Result{}, targetState{}, fmt.Errorf("failed to set service account of %s: %w", deployer.GetKind(), err)
+	}
+
+	// Delete objects of kinds no longer used, e.g. when chart was set
+	for _, gvk := range fluxGVKs {
+		if gvk != source.GroupVersionKind() && gvk != deployer.GroupVersionKind() {
+			if err := b.deleteObject(ctx, logger, cr, gvk); err != nil {
+				return reconcile.Result{}, targetState{}, err
+			}
+		}
+	}
+
+	// Update objects
+	state := targetState{}
+	for _, obj := range []*unstructured.Unstructured{source, deployer} {
+		found, err := b.reconcileObject(ctx, logger, cr, obj, &state)
+		if err != nil {
+			return reconcile.Result{}, state, err
+		}
+
+		// Status of the deployer is the status of the application
+		if obj == deployer && found != nil {
+			state.observed = true
+			state.syncStatus, state.healthStatus = fluxStatus(found)
+			state.syncedRevision, _, _ = unstructured.NestedString(found.Object, "status", "lastAppliedRevision")
+		}
+	}
+
+	return reconcile.Result{}, state, nil
+}
+
+// Returns name of the service account, which deploys applications of given namespace
+func (b *fluxBackend) serviceAccountFor(ctx context.Context, namespace string) (string, error) {
+	ns := &corev1.Namespace{}
+	if err := b.client.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return "", fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+	}
+
+	serviceAccount := ns.Annotations[fluxServiceAccountAnnotation]
+	if len(serviceAccount) == 0 {
+		return "", newPolicyViolationError("namespace %s has no %s annotation, flux backend requires service account to deploy its applications", namespace, fluxServiceAccountAnnotation)
+	}
+	return serviceAccount, nil
+}
+
+// Creates or updates single object, returns found object if it existed already
+func (b *fluxBackend) reconcileObject(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application, obj *unstructured.Unstructured, state *targetState) (*unstructured.Unstructured, error) {
+	kind := obj.GetKind() + "." + obj.GroupVersionKind().Group
+	logger = logger.WithValues(obj.GetKind()+".Namespace", obj.GetNamespace(), obj.GetKind()+".Name", obj.GetName())
+
+	// Check if this object already exists
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(obj.GroupVersionKind())
+	err := b.client.Get(ctx, types.NamespacedName{Name: obj.GetName(), Namespace: obj.GetNamespace()}, found)
+	if err != nil && k8serrors.IsNotFound(err) {
+		// Don't create anything while paused
+		if cond := newPausedCondition(cr, nil, kind); cond != nil {
+			logger.Info("updates are paused, not creating " + kind)
+			state.paused = cond
+			return nil, nil
+		}
+
+		logger.Info("creating a new " + kind)
+		if err := b.applyObject(ctx, obj, state); err != nil || state.conflict != nil {
+			return nil, err
+		}
+
+		return nil, b.addReference(obj, state)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get existing %s: %w", kind, err)
+	}
+
+	// Verify ownership
+	if isApplicationOwnedBy(found, cr) {
+		// Not owned by this CR! This will fail repeatedly, but its ok - should not happen in real-life
+		return nil, newNameConflictError("object %s \"%s\" in namespace \"%s\" already exists, and it is not owned by this object", kind, found.GetName(), found.GetNamespace())
+	}
+
+	if err := b.addReference(found, state); err != nil {
+		return found, err
+	}
+
+	// Don't revert manual changes while paused
+	if cond := newPausedCondition(cr, found, kind); cond != nil {
+		logger.Info("updates are paused, not updating " + kind)
+		state.paused = cond
+		return found, nil
+	}
+
+	// Object exists, apply the whole desired state, so fields no longer set by the operator are removed
+	if err := b.applyObject(ctx, obj, state); err != nil {
+		return found, err
+	}
+	if obj.GetResourceVersion() != found.GetResourceVersion() {
+		logger.Info("updated existing " + kind)
+	}
+
+	return found, nil
+}
+
+// Applies the object, first conflict is stored into the state
+func (b *fluxBackend) applyObject(ctx context.Context, obj *unstructured.Unstructured, state *targetState) error {
+	conflict, err := applyObject(ctx, b.client, obj, false)
+	if conflict != nil && state.conflict == nil {
+		state.conflict = conflict
+	}
+	return err
+}
+
+func (b *fluxBackend) addReference(obj *unstructured.Unstructured, state *targetState) error {
+	ref, err := opsv1alpha1.ReferenceFromObject(obj, obj, b.scheme)
+	if err != nil {
+		return fmt.Errorf("failed build Reference from %s object: %w", obj.GetKind(), err)
+	}
+
+	state.references = append(state.references, ref)
+	return nil
+}
+
+func (b *fluxBackend) finalize(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application) error {
+	// Delete deployers first, so they can clean up while their sources exist
+	for i := len(fluxGVKs) - 1; i >= 0; i-- {
+		if err := b.deleteObject(ctx, logger, cr, fluxGVKs[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Deletes target object of given kind, if it exists and is owned by the CR
+func (b *fluxBackend) deleteObject(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application, gvk schema.GroupVersionKind) error {
+	kind := gvk.Kind + "." + gvk.Group
+
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(gvk)
+	err := b.client.Get(ctx, types.NamespacedName{Name: targetName(cr), Namespace: b.namespace}, found)
+	if err != nil {
+		if k8serrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			// Nothing to delete
+			return nil
+		}
+
+		return fmt.Errorf("failed to get %s for deletion: %w", kind, err)
+	}
+
+	if isApplicationOwnedBy(found, cr) {
+		// Not ours, leave it alone
+		return nil
+	}
+
+	logger.Info("deleting "+kind, gvk.Kind+".Namespace", found.GetNamespace(), gvk.Kind+".Name", found.GetName())
+	if err := b.client.Delete(ctx, found); err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete %s: %w", kind, err)
+	}
+
+	return nil
+}
+
+// Requests reconciliation of the git source of the CR
+func (b *fluxBackend) refresh(ctx context.Context, logger logr.Logger, cr *opsv1alpha1.Application) error {
+	found := &unstructured.Unstructured{}
+	found.SetGroupVersionKind(gitRepositoryGVK)
+	err := b.client.Get(ctx, types.NamespacedName{Name: targetName(cr), Namespace: b.namespace}, found)
+	if err != nil {
+		if k8serrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			// Nothing to refresh
+			return nil
+		}
+		return fmt.Errorf("failed to get GitRepository for refresh: %w", err)
+	}
+	if isApplicationOwnedBy(found, cr) {
+		return nil
+	}
+
+	// Copy instance for patch
+	newObj := found.DeepCopy()
+	annotations := newObj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[reconcileRequestAnnotation] = time.Now().Format(time.RFC3339Nano)
+	newObj.SetAnnotations(annotations)
+
+	logger.Info("refreshing GitRepository", "GitRepository.Namespace", found.GetNamespace(), "GitRepository.Name", found.GetName())
+	if err := b.client.Patch(ctx, newObj, client.MergeFrom(found)); err != nil {
+		return fmt.Errorf("failed to refresh GitRepository: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package application
+
+import (
+	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"regexp"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+var gitRepositoryGVK = schema.GroupVersionKind{Group: "source.toolkit.fluxcd.io", Version: "v1beta1", Kind: "GitRepository"}
+var helmRepositoryGVK = schema.GroupVersionKind{Group: "source.toolkit.fluxcd.io", Version: "v1beta1", Kind: "HelmRepository"}
+var kustomizationGVK = schema.GroupVersionKind{Group: "kustomize.toolkit.fluxcd.io", Version: "v1beta1", Kind: "Kustomization"}
+var helmReleaseGVK = schema.GroupVersionKind{Group: "helm.toolkit.fluxcd.io", Version: "v2beta1", Kind: "HelmRelease"}
+
+// All kinds of objects managed by flux backend, sources first
+var fluxGVKs = []schema.GroupVersionKind{gitRepositoryGVK, helmRepositoryGVK, kustomizationGVK, helmReleaseGVK}
+
+var commitSHARegexp = regexp.MustCompile("^[0-9a-f]{40}$")
+
+// Returns source and deployer objects for the CR, deployer being either Kustomization or HelmRelease
+func (b *fluxBackend) newFluxObjects(cr *opsv1alpha1.Application, opts targetOptions) (*unstructured.Unstructured, *unstructured.Unstructured, error) {
+	src := cr.Spec.Source
+	if src.Ksonnet != nil || src.Plugin != nil || (src.Directory != nil && (len(src.Directory.Jsonnet.ExtVars) > 0 || len(src.Directory.Jsonnet.TLAs) > 0)) {
+		return nil, nil, newInvalidSpecError("only kustomize, helm and plain directory sources are supported by flux backend")
+	}
+	if len(cr.Spec.SyncWindows) > 0 {
+		return nil, nil, newInvalidSpecError("sync windows are not supported by flux backend")
+	}
+	if cr.Spec.Clusters != nil {
+		return nil, nil, newInvalidSpecError("multiple clusters are not supported by flux backend")
+	}
+
+	// Automated sync maps to suspend
+	automated := cr.Spec.SyncPolicy != nil && cr.Spec.SyncPolicy.Automated != nil && !opts.disableAutomatedSync
+	prune := automated && cr.Spec.SyncPolicy.Automated.Prune
+
+	// Helm repository
+	if len(src.Chart) > 0 {
+		source := b.newFluxObject(cr, helmRepositoryGVK, map[string]interface{}{
+			"url":      src.RepoURL,
+			"interval": b.interval.String(),
+		})
+
+		version := src.TargetRevision
+		if len(version) == 0 {
+			version = "*"
+		}
+		deployer, err := b.newHelmRelease(cr, source, src.Chart, version, !automated)
+		return source, deployer, err
+	}
+
+	// Git repository
+	sourceSpec := map[string]interface{}{
+		"url":      src.RepoURL,
+		"interval": b.interval.String(),
+	}
+	if ref := gitRef(src.TargetRevision); ref != nil {
+		sourceSpec["ref"] = ref
+	}
+	source := b.newFluxObject(cr, gitRepositoryGVK, sourceSpec)
+
+	// Helm chart in git
+	if src.Helm != nil {
+		deployer, err := b.newHelmRelease(cr, source, src.Path, "*", !automated)
+		return source, deployer, err
+	}
+
+	// Kustomize or plain directory
+	deployerSpec := map[string]interface{}{
+		"interval":        b.interval.String(),
+		"path":            "./" + strings.TrimPrefix(src.Path, "/"),
+		"prune":           prune,
+		"suspend":         !automated,
+		"targetNamespace": cr.DestinationNamespace(),
+		"sourceRef":       sourceRef(source),
+	}
+	if src.Kustomize != nil && len(src.Kustomize.Images) > 0 {
+		images := make([]interface{}, 0, len(src.Kustomize.Images))
+		for _, image := range src.Kustomize.Images {
+			images = append(images, kustomizeImage(string(image)))
+		}
+		deployerSpec["images"] = images
+	}
+	return source, b.newFluxObject(cr, kustomizationGVK, deployerSpec), nil
+}
+
+func (b *fluxBackend) newHelmRelease(cr *opsv1alpha1.Application, source *unstructured.Unstructured, chart, version string, suspend bool) (*unstructured.Unstructured, error) {
+	spec := map[string]interface{}{
+		"interval": b.interval.String(),
+		"chart": map[string]interface{}{
+			"spec": map[string]interface{}{
+				"chart":     chart,
+				"version":   version,
+				"sourceRef": sourceRef(source),
+			},
+		},
+		"suspend":         suspend,
+		"targetNamespace": cr.DestinationNamespace(),
+	}
+
+	if helm := cr.Spec.Source.Helm; helm != nil {
+		if len(helm.Parameters) > 0 || len(helm.FileParameters) > 0 {
+			return nil, newInvalidSpecError("helm parameters are not supported by flux backend, use values instead")
+		}
+		if len(helm.ValueFiles) > 1 {
+			return nil, newInvalidSpecError("only single helm value file is supported by flux backend")
+		}
+		if len(helm.ValueFiles) == 1 {
+			spec["chart"].(map[string]interface{})["spec"].(map[string]interface{})["valuesFile"] = helm.ValueFiles[0]
+		}
+		if len(helm.ReleaseName) > 0 {
+			spec["releaseName"] = helm.ReleaseName
+		}
+		if len(helm.Values) > 0 {
+			values := map[string]interface{}{}
+			if err := yaml.Unmarshal([]byte(helm.Values), &values); err != nil {
+				return nil, newInvalidSpecError("failed to parse helm values: %w", err)
+			}
+			spec["values"] = values
+		}
+	}
+
+	return b.newFluxObject(cr, helmReleaseGVK, spec), nil
+}
+
+func (b *fluxBackend) newFluxObject(cr *opsv1alpha1.Application, gvk schema.GroupVersionKind, spec map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(targetName(cr))
+	obj.SetNamespace(b.namespace)
+	obj.SetLabels(applicationLabels(cr))
+	return obj
+}
+
+func sourceRef(source *unstructured.Unstructured) map[string]interface{} {
+	return map[string]interface{}{
+		"kind": source.GetKind(),
+		"name": source.GetName(),
+	}
+}
+
+// Converts Argo CD target revision to GitRepository ref, nil means default branch
+func gitRef(revision string) map[string]interface{} {
+	switch {
+	case len(revision) == 0 || revision == "HEAD":
+		return nil
+	case commitSHARegexp.MatchString(revision):
+		return map[string]interface{}{"commit": revision}
+	case strings.HasPrefix(revision, "refs/tags/"):
+		return map[string]interface{}{"tag": strings.TrimPrefix(revision, "refs/tags/")}
+	default:
+		return map[string]interface{}{"branch": strings.TrimPrefix(revision, "refs/heads/")}
+	}
+}
+
+// Converts Argo CD kustomize image override, e.g. nginx=my/nginx:1.2, to Kustomization image
+func kustomizeImage(image string) map[string]interface{} {
+	result := map[string]interface{}{}
+
+	name := image
+	if i := strings.Index(image, "="); i >= 0 {
+		name, image = image[:i], image[i+1:]
+	}
+
+	// Split tag, ignoring registry port
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		result["newTag"] = image[i+1:]
+		image = image[:i]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+
+	result["name"] = name
+	if image != name {
+		result["newName"] = image
+	}
+	return result
+}
+
+// Returns sync and health status of Flux object, based on its Ready condition
+func fluxStatus(obj *unstructured.Unstructured) (argocdv1alpha1.SyncStatusCode, argocdv1alpha1.HealthStatusCode) {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if !ok || cond["type"] != "Ready" {
+			continue
+		}
+
+		switch cond["status"] {
+		case "True":
+			return argocdv1alpha1.SyncStatusCodeSynced, argocdv1alpha1.HealthStatusHealthy
+		case "False":
+			return argocdv1alpha1.SyncStatusCodeOutOfSync, argocdv1alpha1.HealthStatusDegraded
+		}
+	}
+
+	return argocdv1alpha1.SyncStatusCodeUnknown, argocdv1alpha1.HealthStatusProgressing
+}
@@ -0,0 +1,16 @@
+package application
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Number of times generated target objects drifted from the state last applied by the operator
+var driftTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "application_operator_drift_total",
+	Help: "Number of times target objects of Application.ops.csas.cz drifted from the state last applied by the operator",
+}, []string{"namespace", "name"})
+
+func init() {
+	metrics.Registry.MustRegister(driftTotal)
+}
@@ -1,5 +1,9 @@
 package application
 
+import (
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
 func contains(list []string, s string) bool {
 	for _, v := range list {
 		if v == s {
@@ -17,3 +21,12 @@ func remove(list []string, s string) []string {
 	}
 	return list
 }
+
+// Returns result which requeues sooner, or doesn't requeue at all when neither of them does
+func sooner(a, b reconcile.Result) reconcile.Result {
+	if a.RequeueAfter <= 0 || (b.RequeueAfter > 0 && b.RequeueAfter < a.RequeueAfter) {
+		a.RequeueAfter = b.RequeueAfter
+	}
+	a.Requeue = a.Requeue || b.Requeue
+	return a
+}
@@ -0,0 +1,191 @@
+package applicationnamespacestatus
+
+import (
+	"context"
+	"fmt"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sort"
+)
+
+var log = logf.Log.WithName("controller_applicationnamespacestatus")
+
+// Add creates a new ApplicationNamespaceStatus Controller and adds it to the Manager. The Manager will set fields on
+// the Controller and Start it when the Manager is Started.
+func Add(mgr manager.Manager) error {
+	return add(mgr, newReconciler(mgr))
+}
+
+// newReconciler returns a new reconcile.Reconciler
+func newReconciler(mgr manager.Manager) reconcile.Reconciler {
+	return &ReconcileApplicationNamespaceStatus{client: mgr.GetClient()}
+}
+
+// add adds a new Controller to mgr with r as the reconcile.Reconciler
+func add(mgr manager.Manager, r reconcile.Reconciler) error {
+	// Create a new controller
+	c, err := controller.New("applicationnamespacestatus-controller", mgr, controller.Options{Reconciler: r})
+	if err != nil {
+		return fmt.Errorf("failed to create new controller: %w", err)
+	}
+
+	// Watch for changes to primary resource ApplicationNamespaceStatus, so manual changes are reverted
+	err = c.Watch(&source.Kind{Type: &opsv1alpha1.ApplicationNamespaceStatus{}}, &handler.EnqueueRequestForObject{})
+	if err != nil {
+		return fmt.Errorf("failed to watch source objects: %w", err)
+	}
+
+	// Watch for changes of applications and requeue status of their namespace
+	err = c.Watch(&source.Kind{Type: &opsv1alpha1.Application{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(namespaceStatusMapFunc),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch applications: %w", err)
+	}
+
+	return nil
+}
+
+// Maps any object to ApplicationNamespaceStatus in its namespace
+func namespaceStatusMapFunc(obj handler.MapObject) []reconcile.Request {
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Name:      opsv1alpha1.ApplicationNamespaceStatusName,
+		Namespace: obj.Meta.GetNamespace(),
+	}}}
+}
+
+// blank assignment to verify that ReconcileApplicationNamespaceStatus implements reconcile.Reconciler
+var _ reconcile.Reconciler = &ReconcileApplicationNamespaceStatus{}
+
+// ReconcileApplicationNamespaceStatus maintains ApplicationNamespaceStatus object in each namespace with applications
+type ReconcileApplicationNamespaceStatus struct {
+	// This client, initialized using mgr.Client() above, is a split client
+	// that reads objects from the cache and writes to the apiserver
+	client client.Client
+}
+
+// Reconcile summarizes all Application.ops.csas.cz in the namespace of the request into ApplicationNamespaceStatus.
+// The object is deleted when there are no applications in the namespace.
+func (r *ReconcileApplicationNamespaceStatus) Reconcile(request reconcile.Request) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Namespace", request.Namespace, "Request.Name", request.Name)
+
+	ctx := context.TODO()
+
+	// Only the single object is maintained
+	if request.Name != opsv1alpha1.ApplicationNamespaceStatusName {
+		return reconcile.Result{}, nil
+	}
+
+	apps := &opsv1alpha1.ApplicationList{}
+	if err := r.client.List(ctx, apps, client.InNamespace(request.Namespace)); err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to list Application.ops.csas.cz: %w", err)
+	}
+
+	found := &opsv1alpha1.ApplicationNamespaceStatus{}
+	err := r.client.Get(ctx, request.NamespacedName, found)
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return reconcile.Result{}, fmt.Errorf("failed to get ApplicationNamespaceStatus.ops.csas.cz: %w", err)
+	}
+	exists := err == nil
+
+	// No applications, nothing to summarize
+	if len(apps.Items) == 0 {
+		if exists {
+			reqLogger.Info("deleting ApplicationNamespaceStatus.ops.csas.cz")
+			if err := r.client.Delete(ctx, found); err != nil && !k8serrors.IsNotFound(err) {
+				return reconcile.Result{}, fmt.Errorf("failed to delete ApplicationNamespaceStatus.ops.csas.cz: %w", err)
+			}
+		}
+		return reconcile.Result{}, nil
+	}
+
+	newStatus := summarize(apps.Items)
+
+	if !exists {
+		reqLogger.Info("creating a new ApplicationNamespaceStatus.ops.csas.cz")
+		obj := &opsv1alpha1.ApplicationNamespaceStatus{
+			ObjectMeta: metav1.ObjectMeta{Name: request.Name, Namespace: request.Namespace},
+			Status:     newStatus,
+		}
+		if err := r.client.Create(ctx, obj); err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to create ApplicationNamespaceStatus.ops.csas.cz: %w", err)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	// Update only if changed
+	if !equality.Semantic.DeepEqual(found.Status, newStatus) {
+		reqLogger.Info("updating ApplicationNamespaceStatus.ops.csas.cz", "Total", newStatus.Total)
+		found.Status = newStatus
+		if err := r.client.Update(ctx, found); err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to update ApplicationNamespaceStatus.ops.csas.cz: %w", err)
+		}
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// Returns summary of given applications
+func summarize(apps []opsv1alpha1.Application) opsv1alpha1.ApplicationNamespaceStatusStatus {
+	result := opsv1alpha1.ApplicationNamespaceStatusStatus{
+		Applications: make([]opsv1alpha1.ApplicationSummary, 0, len(apps)),
+		Total:        len(apps),
+	}
+
+	for i := range apps {
+		app := &apps[i]
+		result.Applications = append(result.Applications, newApplicationSummary(app))
+
+		switch {
+		case app.Status.Conditions.IsTrueFor(opsv1alpha1.ReadyCondition):
+			result.Ready++
+		case app.Status.Conditions.IsTrueFor(opsv1alpha1.DegradedCondition):
+			result.Degraded++
+		case app.Status.Conditions.IsTrueFor(opsv1alpha1.ProgressingCondition):
+			result.Progressing++
+		}
+	}
+
+	sort.Slice(result.Applications, func(i, j int) bool {
+		return result.Applications[i].Name < result.Applications[j].Name
+	})
+	return result
+}
+
+func newApplicationSummary(app *opsv1alpha1.Application) opsv1alpha1.ApplicationSummary {
+	summary := opsv1alpha1.ApplicationSummary{
+		Name:         app.Name,
+		Target:       targetName(app),
+		SyncStatus:   app.Status.SyncStatus,
+		HealthStatus: app.Status.HealthStatus,
+		Summary:      app.Status.Summary,
+	}
+
+	if cond := app.Status.Conditions.GetCondition(opsv1alpha1.AvailableCondition); cond != nil && cond.IsFalse() && cond.Reason != opsv1alpha1.DeletedReason {
+		summary.LastError = cond.Message
+	}
+	return summary
+}
+
+// Returns name of the generated Argo CD application, or of the first target object of other kinds
+func targetName(app *opsv1alpha1.Application) string {
+	for _, ref := range app.Status.References {
+		if ref.Kind == "Application" {
+			return ref.Name
+		}
+	}
+	if len(app.Status.References) > 0 {
+		return app.Status.References[0].Name
+	}
+	return ""
+}
@@ -0,0 +1,79 @@
+package delivery
+
+import (
+	"errors"
+	"fmt"
+	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
+	"os"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"strings"
+)
+
+//noinspection GoUnusedConst
+const (
+	BackendEnvVar  = "DELIVERY_BACKEND"
+	BackendArgoCD  = "argocd"
+	BackendFlux    = "flux"
+	BackendDefault = BackendArgoCD
+)
+
+// Returns configured delivery backend, which generates target objects from Application.ops.csas.cz
+func GetBackend() (string, error) {
+	if value, ok := os.LookupEnv(BackendEnvVar); ok && len(value) > 0 {
+		if value != BackendArgoCD && value != BackendFlux {
+			return "", fmt.Errorf("%s has unsupported value '%s', must be one of %s, %s", BackendEnvVar, value, BackendArgoCD, BackendFlux)
+		}
+		return value, nil
+	} else {
+		// Default
+		return BackendDefault, nil
+	}
+}
+
+// Adds namespace from given env var to the watched namespaces, unless all namespaces are watched
+func AddNamespaceToWatched(namespaceEnvVar string) error {
+	namespace, ok := os.LookupEnv(namespaceEnvVar)
+	if !ok {
+		return errors.New(fmt.Sprintf("%s not set, cannot add it as watched namespace", namespaceEnvVar))
+	}
+
+	return AddNamespacesToWatched([]string{namespace})
+}
+
+// Adds given namespaces to the watched namespaces, unless all namespaces are watched
+func AddNamespacesToWatched(namespaces []string) error {
+	log := logf.Log.WithName("delivery_env")
+
+	watchNamespace, ok := os.LookupEnv(k8sutil.WatchNamespaceEnvVar)
+	// Empty string means everything is watched
+	if !ok || len(watchNamespace) == 0 {
+		return nil
+	}
+
+	// Add to env variable
+	for _, namespace := range namespaces {
+		if !contains(strings.Split(watchNamespace, ","), namespace) {
+			watchNamespace = watchNamespace + "," + namespace
+
+			// Set env var
+			if err := os.Setenv(k8sutil.WatchNamespaceEnvVar, watchNamespace); err != nil {
+				// Failed
+				return err
+			} else {
+				// OK
+				log.Info(fmt.Sprintf("namespace '%s' is not part of %s, forcefully added", namespace, k8sutil.WatchNamespaceEnvVar))
+			}
+		}
+	}
+
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package flux
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mdvorak/argo-application-operator/pkg/delivery"
+	"os"
+	"time"
+)
+
+//noinspection GoUnusedConst
+const (
+	NamespaceEnvVar = "FLUX_NAMESPACE"
+	IntervalEnvVar  = "FLUX_INTERVAL"
+	IntervalDefault = 5 * time.Minute
+)
+
+func GetNamespace() (string, error) {
+	if value, ok := os.LookupEnv(NamespaceEnvVar); ok && len(value) > 0 {
+		return value, nil
+	} else {
+		return "", errors.New(fmt.Sprintf("%s not set", NamespaceEnvVar))
+	}
+}
+
+// Returns reconciliation interval of generated Flux objects
+func GetInterval() (time.Duration, error) {
+	if value, ok := os.LookupEnv(IntervalEnvVar); ok && len(value) > 0 {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not a valid duration: %w", IntervalEnvVar, err)
+		}
+		return d, nil
+	} else {
+		// Default
+		return IntervalDefault, nil
+	}
+}
+
+func AddNamespaceToWatched() error {
+	return delivery.AddNamespaceToWatched(NamespaceEnvVar)
+}
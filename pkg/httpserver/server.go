@@ -0,0 +1,52 @@
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"time"
+)
+
+var log = logf.Log.WithName("httpserver")
+
+// How long in-flight requests may take to complete after the manager stops
+const shutdownTimeout = 10 * time.Second
+
+// Server serves single handler on given port and path until the manager stops
+type Server struct {
+	// Name of the server in logs, e.g. webhook receiver
+	Name    string
+	Port    int
+	Path    string
+	Handler http.Handler
+}
+
+// blank assignment to verify that Server implements manager.Runnable
+var _ manager.Runnable = &Server{}
+
+// Start listens until stop is closed, it implements manager.Runnable
+func (s *Server) Start(stop <-chan struct{}) error {
+	mux := http.NewServeMux()
+	mux.Handle(s.Path, s.Handler)
+	srv := &http.Server{Addr: fmt.Sprintf(":%d", s.Port), Handler: mux}
+
+	errCh := make(chan error, 1)
+	go func() {
+		log.Info("starting "+s.Name, "Port", s.Port, "Path", s.Path)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case <-stop:
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		return srv.Shutdown(ctx)
+	case err := <-errCh:
+		return err
+	}
+}
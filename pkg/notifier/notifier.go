@@ -0,0 +1,190 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"net/http"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"time"
+)
+
+const (
+	// CloudEvents structured content mode
+	ContentType = "application/cloudevents+json"
+	// Header with hex encoded HMAC-SHA256 of the body, prefixed with sha256=
+	SignatureHeader = "X-Signature-256"
+	// Size of the queue of undelivered events
+	queueSize = 100
+)
+
+var log = logf.Log.WithName("notifier")
+
+// Event in CloudEvents 1.0 JSON format
+type Event struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject,omitempty"`
+	Time            time.Time   `json:"time"`
+	DataContentType string      `json:"datacontenttype,omitempty"`
+	Data            interface{} `json:"data,omitempty"`
+}
+
+// Creates new event with unique ID and current time
+func NewEvent(eventType, source, subject string, data interface{}) Event {
+	return Event{
+		SpecVersion:     "1.0",
+		ID:              string(uuid.NewUUID()),
+		Source:          source,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+}
+
+// Notifier delivers events to an HTTP webhook in background, in the order they were sent
+type Notifier struct {
+	url     string
+	secret  []byte
+	retries int
+	backoff time.Duration
+	client  *http.Client
+	queue   chan Event
+}
+
+// Creates Notifier configured by env vars, nil when the webhook is disabled. It must be started, e.g. by adding
+// it to the manager.
+func NewFromEnv() (*Notifier, error) {
+	url := GetURL()
+	if len(url) == 0 {
+		return nil, nil
+	}
+	retries, err := GetRetries()
+	if err != nil {
+		return nil, err
+	}
+	backoff, err := GetBackoff()
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := GetTimeout()
+	if err != nil {
+		return nil, err
+	}
+
+	return New(url, []byte(GetSecret()), retries, backoff, &http.Client{Timeout: timeout}), nil
+}
+
+// Creates Notifier delivering events to given URL, signed by the secret when it is not empty
+func New(url string, secret []byte, retries int, backoff time.Duration, client *http.Client) *Notifier {
+	return &Notifier{
+		url:     url,
+		secret:  secret,
+		retries: retries,
+		backoff: backoff,
+		client:  client,
+		queue:   make(chan Event, queueSize),
+	}
+}
+
+// blank assignment to verify that Notifier implements manager.Runnable
+var _ manager.Runnable = &Notifier{}
+
+// Start delivers queued events until stop is closed, it implements manager.Runnable
+func (n *Notifier) Start(stop <-chan struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case event := <-n.queue:
+			if err := n.deliver(ctx, event); err != nil {
+				log.Error(err, "failed to deliver event", "Event.ID", event.ID, "Event.Type", event.Type, "Event.Subject", event.Subject)
+			}
+		}
+	}
+}
+
+// Send queues the event for delivery, it never blocks. Events are dropped when the queue is full.
+func (n *Notifier) Send(event Event) {
+	select {
+	case n.queue <- event:
+	default:
+		log.Error(fmt.Errorf("queue is full"), "dropping event", "Event.ID", event.ID, "Event.Type", event.Type, "Event.Subject", event.Subject)
+	}
+}
+
+// Delivers single event, retrying with exponential backoff
+func (n *Notifier) deliver(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	delay := n.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := n.post(ctx, body)
+		if err == nil || !retry || attempt >= n.retries {
+			return err
+		}
+
+		log.Info("event delivery failed, retrying", "Event.ID", event.ID, "Attempt", attempt+1, "Error", err.Error())
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// Posts the body to the webhook, returns whether failed delivery should be retried. Client errors are permanent,
+// sending the same request again would not help.
+func (n *Notifier) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", ContentType)
+	if len(n.secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(n.secret, body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode >= 500, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return false, nil
+}
+
+// Returns hex encoded HMAC-SHA256 of the body
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,67 @@
+package notifier
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+//noinspection GoUnusedConst
+const (
+	URLEnvVar      = "STATUS_WEBHOOK_URL"
+	SecretEnvVar   = "STATUS_WEBHOOK_SECRET"
+	RetriesEnvVar  = "STATUS_WEBHOOK_RETRIES"
+	RetriesDefault = 5
+	BackoffEnvVar  = "STATUS_WEBHOOK_BACKOFF"
+	BackoffDefault = time.Second
+	TimeoutEnvVar  = "STATUS_WEBHOOK_TIMEOUT"
+	TimeoutDefault = 10 * time.Second
+)
+
+// Returns URL of the status webhook, empty when it is disabled
+func GetURL() string {
+	return os.Getenv(URLEnvVar)
+}
+
+// Returns secret used to sign webhook requests, empty when they are not signed
+func GetSecret() string {
+	return os.Getenv(SecretEnvVar)
+}
+
+// Returns how many times failed delivery is retried
+func GetRetries() (int, error) {
+	if value, ok := os.LookupEnv(RetriesEnvVar); ok && len(value) > 0 {
+		retries, err := strconv.Atoi(value)
+		if err != nil || retries < 0 {
+			return 0, fmt.Errorf("%s is not a valid number of retries: %s", RetriesEnvVar, value)
+		}
+		return retries, nil
+	} else {
+		// Default
+		return RetriesDefault, nil
+	}
+}
+
+// Returns delay before the first retry, it doubles with each attempt
+func GetBackoff() (time.Duration, error) {
+	return getDuration(BackoffEnvVar, BackoffDefault)
+}
+
+// Returns timeout of a single request
+func GetTimeout() (time.Duration, error) {
+	return getDuration(TimeoutEnvVar, TimeoutDefault)
+}
+
+func getDuration(envVar string, defaultValue time.Duration) (time.Duration, error) {
+	if value, ok := os.LookupEnv(envVar); ok && len(value) > 0 {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not a valid duration: %w", envVar, err)
+		}
+		return d, nil
+	} else {
+		// Default
+		return defaultValue, nil
+	}
+}
@@ -0,0 +1,167 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Records requests and responds with given status codes, the last one repeats
+type recordingServer struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   [][]byte
+	headers  []http.Header
+	times    []time.Time
+}
+
+func (s *recordingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bodies = append(s.bodies, body)
+	s.headers = append(s.headers, r.Header)
+	s.times = append(s.times, time.Now())
+
+	status := s.statuses[len(s.statuses)-1]
+	if len(s.bodies) <= len(s.statuses) {
+		status = s.statuses[len(s.bodies)-1]
+	}
+	w.WriteHeader(status)
+}
+
+// Returns notifier delivering to a local server, which must be closed
+func newTestNotifier(secret string, retries int, statuses ...int) (*Notifier, *recordingServer, *httptest.Server) {
+	rec := &recordingServer{statuses: statuses}
+	srv := httptest.NewServer(rec)
+	return New(srv.URL, []byte(secret), retries, 10*time.Millisecond, srv.Client()), rec, srv
+}
+
+func TestDeliverEvent(t *testing.T) {
+	n, rec, srv := newTestNotifier("s3cret", 3, http.StatusOK)
+	defer srv.Close()
+	event := NewEvent("cz.csas.ops.application.synced", "/apis/ops.csas.cz/v1alpha1/namespaces/foo/applications/guestbook", "foo/guestbook", map[string]string{"sync": "Synced"})
+
+	if err := n.deliver(context.Background(), event); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.bodies) != 1 {
+		t.Fatalf("expected single request, got %d", len(rec.bodies))
+	}
+
+	// Headers
+	if got := rec.headers[0].Get("Content-Type"); got != ContentType {
+		t.Errorf("expected content type %s, got %s", ContentType, got)
+	}
+	if got, expected := rec.headers[0].Get(SignatureHeader), "sha256="+Sign([]byte("s3cret"), rec.bodies[0]); got != expected {
+		t.Errorf("expected signature %s, got %s", expected, got)
+	}
+
+	// CloudEvents body
+	body := map[string]interface{}{}
+	if err := json.Unmarshal(rec.bodies[0], &body); err != nil {
+		t.Fatalf("invalid body: %v", err)
+	}
+	for key, expected := range map[string]interface{}{
+		"specversion":     "1.0",
+		"id":              event.ID,
+		"source":          event.Source,
+		"type":            event.Type,
+		"subject":         event.Subject,
+		"datacontenttype": "application/json",
+	} {
+		if body[key] != expected {
+			t.Errorf("expected %s to be %v, got %v", key, expected, body[key])
+		}
+	}
+	if _, err := time.Parse(time.RFC3339, body["time"].(string)); err != nil {
+		t.Errorf("invalid time: %v", err)
+	}
+	if data, ok := body["data"].(map[string]interface{}); !ok || data["sync"] != "Synced" {
+		t.Errorf("unexpected data %v", body["data"])
+	}
+}
+
+func TestDeliverUnsigned(t *testing.T) {
+	n, rec, srv := newTestNotifier("", 0, http.StatusNoContent)
+	defer srv.Close()
+
+	if err := n.deliver(context.Background(), NewEvent("test", "test", "", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rec.headers[0][SignatureHeader]; ok {
+		t.Errorf("expected no %s header without secret", SignatureHeader)
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		retries  int
+		statuses []int
+		requests int
+		failed   bool
+	}{
+		{"server error is retried", 3, []int{500, 503, 200}, 3, false},
+		{"retries are exhausted", 2, []int{500}, 3, true},
+		{"client error is not retried", 3, []int{400, 200}, 1, true},
+		{"not found is not retried", 3, []int{404, 200}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, rec, srv := newTestNotifier("", tt.retries, tt.statuses...)
+			defer srv.Close()
+
+			err := n.deliver(context.Background(), NewEvent("test", "test", "", nil))
+			if (err != nil) != tt.failed {
+				t.Errorf("expected failure %v, got %v", tt.failed, err)
+			}
+			if len(rec.bodies) != tt.requests {
+				t.Errorf("expected %d requests, got %d", tt.requests, len(rec.bodies))
+			}
+		})
+	}
+}
+
+func TestDeliverBackoff(t *testing.T) {
+	n, rec, srv := newTestNotifier("", 3, http.StatusInternalServerError)
+	defer srv.Close()
+
+	if err := n.deliver(context.Background(), NewEvent("test", "test", "", nil)); err == nil {
+		t.Fatal("expected failure")
+	}
+	if len(rec.times) != 4 {
+		t.Fatalf("expected 4 requests, got %d", len(rec.times))
+	}
+
+	// Delay doubles with each attempt
+	delay := n.backoff
+	for i := 1; i < len(rec.times); i++ {
+		if elapsed := rec.times[i].Sub(rec.times[i-1]); elapsed < delay {
+			t.Errorf("expected attempt %d after at least %s, got %s", i+1, delay, elapsed)
+		}
+		delay *= 2
+	}
+}
+
+func TestDeliverTransportErrorIsRetried(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	n := New(url, nil, 1, time.Millisecond, &http.Client{Timeout: time.Second})
+	start := time.Now()
+	if err := n.deliver(context.Background(), NewEvent("test", "test", "", nil)); err == nil {
+		t.Fatal("expected failure")
+	}
+	if elapsed := time.Since(start); elapsed < n.backoff {
+		t.Errorf("expected retry after %s, finished in %s", n.backoff, elapsed)
+	}
+}
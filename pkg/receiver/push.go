@@ -0,0 +1,268 @@
+package receiver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Returned when the request signature is missing or invalid
+var ErrUnauthorized = errors.New("invalid webhook signature")
+
+// Returned for valid requests, which are not push events
+var ErrUnsupportedEvent = errors.New("unsupported webhook event")
+
+// PushEvent is a provider independent description of a git push
+type PushEvent struct {
+	// URLs of the repository, e.g. web, https clone and ssh clone URL
+	RepoURLs []string
+	// Names of pushed branches and tags, without refs/heads/ or refs/tags/ prefix
+	Revisions []string
+	// Default branch of the repository, empty when the provider does not send it
+	DefaultBranch string
+}
+
+// MatchesRevision returns true when given target revision was pushed. Empty and HEAD revision match the default branch,
+// or any branch when it is not known.
+func (e *PushEvent) MatchesRevision(targetRevision string) bool {
+	revision := shortRef(targetRevision)
+	if revision == "" || revision == "HEAD" {
+		if e.DefaultBranch == "" {
+			return len(e.Revisions) > 0
+		}
+		revision = e.DefaultBranch
+	}
+
+	for _, r := range e.Revisions {
+		if r == revision {
+			return true
+		}
+	}
+	return false
+}
+
+// ParsePush verifies and parses push event sent by GitHub, GitLab, Bitbucket Cloud or Bitbucket Server
+func ParsePush(req *http.Request, secret string) (*PushEvent, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, req.Body, maxPayloadSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payload: %w", err)
+	}
+
+	switch {
+	case req.Header.Get("X-GitHub-Event") != "":
+		if !verifyHMAC(req.Header.Get("X-Hub-Signature-256"), secret, body) {
+			return nil, ErrUnauthorized
+		}
+		if req.Header.Get("X-GitHub-Event") != "push" {
+			return nil, ErrUnsupportedEvent
+		}
+		return parseGitHub(body)
+	case req.Header.Get("X-Gitlab-Event") != "":
+		if subtle.ConstantTimeCompare([]byte(req.Header.Get("X-Gitlab-Token")), []byte(secret)) != 1 {
+			return nil, ErrUnauthorized
+		}
+		if event := req.Header.Get("X-Gitlab-Event"); event != "Push Hook" && event != "Tag Push Hook" {
+			return nil, ErrUnsupportedEvent
+		}
+		return parseGitLab(body)
+	case req.Header.Get("X-Event-Key") != "" && req.Header.Get("X-Hook-UUID") != "":
+		// Bitbucket Cloud does not sign payloads, the webhook UUID serves as the secret
+		if subtle.ConstantTimeCompare([]byte(req.Header.Get("X-Hook-UUID")), []byte(secret)) != 1 {
+			return nil, ErrUnauthorized
+		}
+		if req.Header.Get("X-Event-Key") != "repo:push" {
+			return nil, ErrUnsupportedEvent
+		}
+		return parseBitbucket(body)
+	case req.Header.Get("X-Event-Key") != "":
+		if !verifyHMAC(req.Header.Get("X-Hub-Signature"), secret, body) {
+			return nil, ErrUnauthorized
+		}
+		if req.Header.Get("X-Event-Key") != "repo:refs_changed" {
+			return nil, ErrUnsupportedEvent
+		}
+		return parseBitbucketServer(body)
+	default:
+		return nil, ErrUnsupportedEvent
+	}
+}
+
+// Verifies sha256=<hex> signature header
+func verifyHMAC(signature, secret string, body []byte) bool {
+	const prefix = "sha256="
+	if !strings.HasPrefix(signature, prefix) {
+		return false
+	}
+	expected, err := hex.DecodeString(signature[len(prefix):])
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+func parseGitHub(body []byte) (*PushEvent, error) {
+	var payload struct {
+		Ref        string `json:"ref"`
+		Repository struct {
+			HTMLURL       string `json:"html_url"`
+			CloneURL      string `json:"clone_url"`
+			SSHURL        string `json:"ssh_url"`
+			DefaultBranch string `json:"default_branch"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub payload: %w", err)
+	}
+
+	return &PushEvent{
+		RepoURLs:      []string{payload.Repository.HTMLURL, payload.Repository.CloneURL, payload.Repository.SSHURL},
+		Revisions:     []string{shortRef(payload.Ref)},
+		DefaultBranch: payload.Repository.DefaultBranch,
+	}, nil
+}
+
+func parseGitLab(body []byte) (*PushEvent, error) {
+	var payload struct {
+		Ref     string `json:"ref"`
+		Project struct {
+			WebURL        string `json:"web_url"`
+			HTTPURL       string `json:"git_http_url"`
+			SSHURL        string `json:"git_ssh_url"`
+			DefaultBranch string `json:"default_branch"`
+		} `json:"project"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse GitLab payload: %w", err)
+	}
+
+	return &PushEvent{
+		RepoURLs:      []string{payload.Project.WebURL, payload.Project.HTTPURL, payload.Project.SSHURL},
+		Revisions:     []string{shortRef(payload.Ref)},
+		DefaultBranch: payload.Project.DefaultBranch,
+	}, nil
+}
+
+func parseBitbucket(body []byte) (*PushEvent, error) {
+	var payload struct {
+		Push struct {
+			Changes []struct {
+				New *struct {
+					Name string `json:"name"`
+				} `json:"new"`
+			} `json:"changes"`
+		} `json:"push"`
+		Repository struct {
+			FullName string `json:"full_name"`
+			Links    struct {
+				HTML struct {
+					Href string `json:"href"`
+				} `json:"html"`
+			} `json:"links"`
+			MainBranch *struct {
+				Name string `json:"name"`
+			} `json:"mainbranch"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse Bitbucket payload: %w", err)
+	}
+
+	event := &PushEvent{
+		RepoURLs: []string{payload.Repository.Links.HTML.Href, "git@bitbucket.org:" + payload.Repository.FullName},
+	}
+	for _, change := range payload.Push.Changes {
+		// New is nil for deleted refs
+		if change.New != nil {
+			event.Revisions = append(event.Revisions, change.New.Name)
+		}
+	}
+	if payload.Repository.MainBranch != nil {
+		event.DefaultBranch = payload.Repository.MainBranch.Name
+	}
+	return event, nil
+}
+
+func parseBitbucketServer(body []byte) (*PushEvent, error) {
+	var payload struct {
+		Changes []struct {
+			Ref struct {
+				ID string `json:"id"`
+			} `json:"ref"`
+			Type string `json:"type"`
+		} `json:"changes"`
+		Repository struct {
+			Links struct {
+				Clone []struct {
+					Href string `json:"href"`
+				} `json:"clone"`
+			} `json:"links"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse Bitbucket Server payload: %w", err)
+	}
+
+	event := &PushEvent{}
+	for _, link := range payload.Repository.Links.Clone {
+		event.RepoURLs = append(event.RepoURLs, link.Href)
+	}
+	for _, change := range payload.Changes {
+		if change.Type != "DELETE" {
+			event.Revisions = append(event.Revisions, shortRef(change.Ref.ID))
+		}
+	}
+	return event, nil
+}
+
+// Strips refs/heads/ or refs/tags/ prefix
+func shortRef(ref string) string {
+	ref = strings.TrimPrefix(ref, "refs/heads/")
+	return strings.TrimPrefix(ref, "refs/tags/")
+}
+
+// NormalizeRepoURL returns host and path of the git repository URL, so https and ssh URLs of the same repository
+// are equal. Returns empty string for invalid URLs.
+func NormalizeRepoURL(repoURL string) string {
+	repoURL = strings.TrimSpace(repoURL)
+	if repoURL == "" {
+		return ""
+	}
+
+	var host, path string
+	if !strings.Contains(repoURL, "://") {
+		// scp-like syntax, e.g. git@github.com:org/repo.git
+		i := strings.Index(repoURL, ":")
+		if i < 0 {
+			return ""
+		}
+		host = repoURL[:i]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		path = repoURL[i+1:]
+	} else {
+		u, err := url.Parse(repoURL)
+		if err != nil {
+			return ""
+		}
+		host = u.Hostname()
+		path = u.Path
+	}
+
+	path = strings.Trim(path, "/")
+	path = strings.TrimSuffix(path, ".git")
+	// Bitbucket Server clone URLs contain /scm/ prefix, which web and ssh URLs don't
+	path = strings.TrimPrefix(path, "scm/")
+	return strings.ToLower(host + "/" + path)
+}
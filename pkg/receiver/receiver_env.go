@@ -0,0 +1,55 @@
+package receiver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+//noinspection GoUnusedConst
+const (
+	EnabledEnvVar  = "RECEIVER_ENABLED"
+	PortEnvVar     = "RECEIVER_PORT"
+	PortDefault    = 8090
+	SecretEnvVar   = "RECEIVER_SECRET"
+	ReceiverPath   = "/api/webhook"
+	maxPayloadSize = 10 * 1024 * 1024
+)
+
+// Returns true when git push webhook receiver is enabled
+func GetEnabled() (bool, error) {
+	if value, ok := os.LookupEnv(EnabledEnvVar); ok && len(value) > 0 {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, fmt.Errorf("%s is not a valid boolean: %w", EnabledEnvVar, err)
+		}
+		return enabled, nil
+	} else {
+		// Default
+		return false, nil
+	}
+}
+
+// Returns port the receiver listens on
+func GetPort() (int, error) {
+	if value, ok := os.LookupEnv(PortEnvVar); ok && len(value) > 0 {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not a valid port: %w", PortEnvVar, err)
+		}
+		return port, nil
+	} else {
+		// Default
+		return PortDefault, nil
+	}
+}
+
+// Returns secret used to verify webhook requests, it is required
+func GetSecret() (string, error) {
+	if value, ok := os.LookupEnv(SecretEnvVar); ok && len(value) > 0 {
+		return value, nil
+	} else {
+		return "", errors.New(fmt.Sprintf("%s not set", SecretEnvVar))
+	}
+}
@@ -0,0 +1,81 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"time"
+)
+
+// Report lists all Application.ops.csas.cz and what they deploy
+type Report struct {
+	GeneratedAt  metav1.Time  `json:"generatedAt"`
+	Applications []Deployment `json:"applications"`
+}
+
+// Deployment describes single Application.ops.csas.cz and its target objects
+type Deployment struct {
+	// Namespace of the Application.ops.csas.cz, which owns the deployment
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Namespace the application is deployed into
+	DestinationNamespace string `json:"destinationNamespace"`
+	// Generated Argo CD applications, or other target objects of the delivery backend
+	Targets []opsv1alpha1.Reference `json:"targets"`
+
+	RepoURL        string                          `json:"repoURL"`
+	Path           string                          `json:"path,omitempty"`
+	Chart          string                          `json:"chart,omitempty"`
+	TargetRevision string                          `json:"targetRevision,omitempty"`
+	SyncedRevision string                          `json:"syncedRevision,omitempty"`
+	SyncStatus     argocdv1alpha1.SyncStatusCode   `json:"syncStatus,omitempty"`
+	HealthStatus   argocdv1alpha1.HealthStatusCode `json:"healthStatus,omitempty"`
+	Summary        string                          `json:"summary,omitempty"`
+}
+
+// Build creates report of all Application.ops.csas.cz visible to given reader. Within the operator, the reader is
+// the manager client, so the report is built from its cache. Options can limit listed applications, e.g. to a namespace.
+func Build(ctx context.Context, reader client.Reader, opts ...client.ListOption) (*Report, error) {
+	apps := &opsv1alpha1.ApplicationList{}
+	if err := reader.List(ctx, apps, opts...); err != nil {
+		return nil, fmt.Errorf("failed to list Application.ops.csas.cz: %w", err)
+	}
+
+	report := &Report{
+		GeneratedAt:  metav1.NewTime(time.Now().Truncate(time.Second)),
+		Applications: make([]Deployment, 0, len(apps.Items)),
+	}
+	for i := range apps.Items {
+		report.Applications = append(report.Applications, newDeployment(&apps.Items[i]))
+	}
+
+	sort.Slice(report.Applications, func(i, j int) bool {
+		a, b := report.Applications[i], report.Applications[j]
+		return a.Namespace < b.Namespace || (a.Namespace == b.Namespace && a.Name < b.Name)
+	})
+	return report, nil
+}
+
+func newDeployment(app *opsv1alpha1.Application) Deployment {
+	targets := make([]opsv1alpha1.Reference, len(app.Status.References))
+	copy(targets, app.Status.References)
+
+	return Deployment{
+		Namespace:            app.Namespace,
+		Name:                 app.Name,
+		DestinationNamespace: app.DestinationNamespace(),
+		Targets:              targets,
+		RepoURL:              app.Spec.Source.RepoURL,
+		Path:                 app.Spec.Source.Path,
+		Chart:                app.Spec.Source.Chart,
+		TargetRevision:       app.Spec.Source.TargetRevision,
+		SyncedRevision:       app.Status.SyncedRevision,
+		SyncStatus:           app.Status.SyncStatus,
+		HealthStatus:         app.Status.HealthStatus,
+		Summary:              app.Status.Summary,
+	}
+}
@@ -0,0 +1,54 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+//noinspection GoUnusedConst
+const (
+	EnabledEnvVar = "REPORT_ENABLED"
+	PortEnvVar    = "REPORT_PORT"
+	PortDefault   = 8091
+	TokenEnvVar   = "REPORT_TOKEN"
+	ReportPath    = "/api/report"
+)
+
+// Returns true when report endpoint is enabled
+func GetEnabled() (bool, error) {
+	if value, ok := os.LookupEnv(EnabledEnvVar); ok && len(value) > 0 {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, fmt.Errorf("%s is not a valid boolean: %w", EnabledEnvVar, err)
+		}
+		return enabled, nil
+	} else {
+		// Default
+		return false, nil
+	}
+}
+
+// Returns port the report endpoint listens on
+func GetPort() (int, error) {
+	if value, ok := os.LookupEnv(PortEnvVar); ok && len(value) > 0 {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not a valid port: %w", PortEnvVar, err)
+		}
+		return port, nil
+	} else {
+		// Default
+		return PortDefault, nil
+	}
+}
+
+// Returns bearer token required by the report endpoint, it is required
+func GetToken() (string, error) {
+	if value, ok := os.LookupEnv(TokenEnvVar); ok && len(value) > 0 {
+		return value, nil
+	} else {
+		return "", errors.New(fmt.Sprintf("%s not set", TokenEnvVar))
+	}
+}
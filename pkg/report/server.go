@@ -0,0 +1,71 @@
+package report
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/mdvorak/argo-application-operator/pkg/httpserver"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"strings"
+)
+
+var log = logf.Log.WithName("report")
+
+// Prefix of the Authorization header value
+const bearerPrefix = "Bearer "
+
+// Handler serves the report as JSON, to requests authorized by the bearer token
+type Handler struct {
+	Reader client.Reader
+	Token  string
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, bearerPrefix) ||
+		subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, bearerPrefix)), []byte(h.Token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	report, err := Build(req.Context(), h.Reader)
+	if err != nil {
+		log.Error(err, "failed to build report")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Error(err, "failed to write report")
+	}
+}
+
+// AddToManager adds the report endpoint to the manager. Report is built from the manager cache.
+func AddToManager(mgr manager.Manager) error {
+	port, err := GetPort()
+	if err != nil {
+		return err
+	}
+	token, err := GetToken()
+	if err != nil {
+		return fmt.Errorf("report endpoint requires token: %w", err)
+	}
+
+	return mgr.Add(&httpserver.Server{
+		Name:    "report endpoint",
+		Port:    port,
+		Path:    ReportPath,
+		Handler: &Handler{Reader: mgr.GetClient(), Token: token},
+	})
+}
@@ -0,0 +1,10 @@
+package webhook
+
+import (
+	"github.com/mdvorak/argo-application-operator/pkg/webhook/application"
+)
+
+func init() {
+	// AddToManagerFuncs is a list of functions to register webhooks in a manager.
+	AddToManagerFuncs = append(AddToManagerFuncs, application.Add)
+}
@@ -0,0 +1,108 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	argocdv1alpha1 "github.com/argoproj/argo-cd/pkg/apis/application/v1alpha1"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+	"sort"
+)
+
+// Namespace annotations with default values of ApplicationSpec, in JSON or YAML
+const (
+	defaultSyncPolicyAnnotation        = "application.ops.csas.cz/default-sync-policy"
+	defaultIgnoreDifferencesAnnotation = "application.ops.csas.cz/default-ignore-differences"
+	defaultInfoAnnotation              = "application.ops.csas.cz/default-info"
+	defaultTargetRevisionAnnotation    = "application.ops.csas.cz/default-target-revision"
+)
+
+// Fills missing fields of the CR spec with defaults of its namespace
+func (w *applicationWebhook) applyDefaults(ctx context.Context, cr *opsv1alpha1.Application) error {
+	defaults, err := w.defaultsFor(ctx, cr.Namespace)
+	if err != nil {
+		return err
+	}
+
+	if cr.Spec.SyncPolicy == nil && defaults.SyncPolicy != nil {
+		cr.Spec.SyncPolicy = defaults.SyncPolicy.DeepCopy()
+	}
+	if len(cr.Spec.IgnoreDifferences) == 0 && len(defaults.IgnoreDifferences) > 0 {
+		cr.Spec.IgnoreDifferences = append([]argocdv1alpha1.ResourceIgnoreDifferences{}, defaults.IgnoreDifferences...)
+	}
+	if len(cr.Spec.Info) == 0 && len(defaults.Info) > 0 {
+		cr.Spec.Info = append([]argocdv1alpha1.Info{}, defaults.Info...)
+	}
+	if len(cr.Spec.Source.TargetRevision) == 0 {
+		cr.Spec.Source.TargetRevision = defaults.TargetRevision
+	}
+	return nil
+}
+
+// Returns defaults of given namespace. ApplicationDefaults objects, ordered by name, take precedence over namespace
+// annotations.
+func (w *applicationWebhook) defaultsFor(ctx context.Context, namespace string) (*opsv1alpha1.ApplicationDefaultsSpec, error) {
+	result := &opsv1alpha1.ApplicationDefaultsSpec{}
+
+	list := &opsv1alpha1.ApplicationDefaultsList{}
+	if err := w.reader.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list ApplicationDefaults.ops.csas.cz: %w", err)
+	}
+	sort.Slice(list.Items, func(i, j int) bool {
+		return list.Items[i].Name < list.Items[j].Name
+	})
+	for i := range list.Items {
+		mergeDefaults(result, &list.Items[i].Spec)
+	}
+
+	ns := &corev1.Namespace{}
+	if err := w.reader.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return nil, fmt.Errorf("failed to get namespace %s: %w", namespace, err)
+	}
+	annotated, err := defaultsFromAnnotations(ns.Annotations)
+	if err != nil {
+		return nil, fmt.Errorf("invalid defaults of namespace %s: %w", namespace, err)
+	}
+	mergeDefaults(result, annotated)
+
+	return result, nil
+}
+
+func defaultsFromAnnotations(annotations map[string]string) (*opsv1alpha1.ApplicationDefaultsSpec, error) {
+	defaults := &opsv1alpha1.ApplicationDefaultsSpec{
+		TargetRevision: annotations[defaultTargetRevisionAnnotation],
+	}
+
+	for annotation, into := range map[string]interface{}{
+		defaultSyncPolicyAnnotation:        &defaults.SyncPolicy,
+		defaultIgnoreDifferencesAnnotation: &defaults.IgnoreDifferences,
+		defaultInfoAnnotation:              &defaults.Info,
+	} {
+		if value, ok := annotations[annotation]; ok && len(value) > 0 {
+			if err := yaml.Unmarshal([]byte(value), into); err != nil {
+				return nil, fmt.Errorf("failed to parse %s annotation: %w", annotation, err)
+			}
+		}
+	}
+
+	return defaults, nil
+}
+
+// Sets fields of into, which are not set yet
+func mergeDefaults(into, from *opsv1alpha1.ApplicationDefaultsSpec) {
+	if into.SyncPolicy == nil {
+		into.SyncPolicy = from.SyncPolicy
+	}
+	if len(into.IgnoreDifferences) == 0 {
+		into.IgnoreDifferences = from.IgnoreDifferences
+	}
+	if len(into.Info) == 0 {
+		into.Info = from.Info
+	}
+	if len(into.TargetRevision) == 0 {
+		into.TargetRevision = from.TargetRevision
+	}
+}
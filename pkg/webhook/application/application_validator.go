@@ -0,0 +1,98 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"net/http"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Access which the author of Application.ops.csas.cz must have in the destination namespace
+var requiredAccess = authorizationv1.ResourceAttributes{
+	Verb:     "create",
+	Group:    "apps",
+	Resource: "deployments",
+}
+
+// blank assignment to verify that applicationValidator implements admission.Handler
+var _ admission.Handler = &applicationValidator{}
+
+// applicationValidator verifies that the author of Application.ops.csas.cz may deploy into its destination
+type applicationValidator struct {
+	client  client.Client
+	decoder *admission.Decoder
+}
+
+// InjectDecoder implements admission.DecoderInjector
+func (v *applicationValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle implements admission.Handler
+func (v *applicationValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	cr := &opsv1alpha1.Application{}
+	if err := v.decoder.Decode(req, cr); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	// Keep existing approval when spec does not change, e.g. when the operator updates finalizers
+	if req.Operation == admissionv1beta1.Update {
+		old := &opsv1alpha1.Application{}
+		if err := v.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if reflect.DeepEqual(old.Spec, cr.Spec) {
+			return admission.Allowed("")
+		}
+	}
+
+	// Verify access of the user
+	destination := cr.DestinationNamespace()
+	allowed, reason, err := v.checkAccess(ctx, req.UserInfo, destination)
+	if err != nil {
+		log.Error(err, "failed to review access", "Request.Namespace", req.Namespace, "Request.Name", req.Name)
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if !allowed {
+		msg := fmt.Sprintf("user %s cannot %s %s.%s in namespace %s", req.UserInfo.Username, requiredAccess.Verb, requiredAccess.Resource, requiredAccess.Group, destination)
+		if len(reason) > 0 {
+			msg += ": " + reason
+		}
+		return admission.Denied(msg)
+	}
+
+	return admission.Allowed("")
+}
+
+// Performs SubjectAccessReview of the user against given namespace, returns whether it is allowed and why
+func (v *applicationValidator) checkAccess(ctx context.Context, user authenticationv1.UserInfo, namespace string) (bool, string, error) {
+	attributes := requiredAccess
+	attributes.Namespace = namespace
+
+	extra := make(map[string]authorizationv1.ExtraValue, len(user.Extra))
+	for key, value := range user.Extra {
+		extra[key] = authorizationv1.ExtraValue(value)
+	}
+
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			ResourceAttributes: &attributes,
+			User:               user.Username,
+			Groups:             user.Groups,
+			UID:                user.UID,
+			Extra:              extra,
+		},
+	}
+	if err := v.client.Create(ctx, review); err != nil {
+		return false, "", fmt.Errorf("failed to create SubjectAccessReview: %w", err)
+	}
+
+	return review.Status.Allowed, review.Status.Reason, nil
+}
@@ -0,0 +1,128 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	"net/http"
+	"reflect"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"strings"
+)
+
+// Path of the mutating webhook of Application.ops.csas.cz
+const MutatePath = "/mutate-ops-csas-cz-v1alpha1-application"
+
+// Path of the validating webhook of Application.ops.csas.cz
+const ValidatePath = "/validate-ops-csas-cz-v1alpha1-application"
+
+var log = logf.Log.WithName("webhook_application")
+
+// Add registers the webhooks of Application.ops.csas.cz in the webhook server of the Manager
+func Add(mgr manager.Manager) error {
+	mgr.GetWebhookServer().Register(MutatePath, &webhook.Admission{Handler: &applicationWebhook{reader: mgr.GetAPIReader()}})
+	mgr.GetWebhookServer().Register(ValidatePath, &webhook.Admission{Handler: &applicationValidator{client: mgr.GetClient()}})
+	return nil
+}
+
+// blank assignment to verify that applicationWebhook implements admission.Handler
+var _ admission.Handler = &applicationWebhook{}
+
+// applicationWebhook applies namespace defaults to Application.ops.csas.cz, and records the approval, creator and
+// last modifier in annotations. Access of the author is verified by applicationValidator, which sees the result.
+type applicationWebhook struct {
+	// Reader of namespaces and defaults, which reads directly from the apiserver
+	reader  client.Reader
+	decoder *admission.Decoder
+}
+
+// InjectDecoder implements admission.DecoderInjector
+func (w *applicationWebhook) InjectDecoder(d *admission.Decoder) error {
+	w.decoder = d
+	return nil
+}
+
+// Handle implements admission.Handler
+func (w *applicationWebhook) Handle(ctx context.Context, req admission.Request) admission.Response {
+	cr := &opsv1alpha1.Application{}
+	if err := w.decoder.Decode(req, cr); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	newCR := cr.DeepCopy()
+
+	// Never trust annotations sent by the user
+	old := &opsv1alpha1.Application{}
+	if req.Operation == admissionv1beta1.Update {
+		if err := w.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	copyWebhookAnnotations(old, newCR)
+	recordPausedBy(old, newCR, req.UserInfo.Username)
+
+	// Keep existing annotations when spec does not change, e.g. when the operator updates finalizers
+	if req.Operation == admissionv1beta1.Update && reflect.DeepEqual(old.Spec, cr.Spec) {
+		return patchResponse(req, newCR)
+	}
+
+	// Fill missing fields, so stored object shows effective values
+	if err := w.applyDefaults(ctx, newCR); err != nil {
+		log.Error(err, "failed to apply defaults", "Request.Namespace", req.Namespace, "Request.Name", req.Name)
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	// Record approval and author
+	annotations := newCR.GetAnnotations()
+	annotations[opsv1alpha1.ApprovedByAnnotation] = req.UserInfo.Username
+	annotations[opsv1alpha1.ApprovedGroupsAnnotation] = strings.Join(req.UserInfo.Groups, ",")
+	if req.Operation == admissionv1beta1.Create {
+		annotations[opsv1alpha1.CreatedByAnnotation] = req.UserInfo.Username
+	}
+	annotations[opsv1alpha1.ModifiedByAnnotation] = req.UserInfo.Username
+	newCR.SetAnnotations(annotations)
+
+	return patchResponse(req, newCR)
+}
+
+// Replaces annotations managed by the webhook with values from the old object, which is empty on create
+func copyWebhookAnnotations(old, cr *opsv1alpha1.Application) {
+	annotations := cr.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	for _, annotation := range opsv1alpha1.WebhookAnnotations {
+		if value, ok := old.Annotations[annotation]; ok {
+			annotations[annotation] = value
+		} else {
+			delete(annotations, annotation)
+		}
+	}
+
+	cr.SetAnnotations(annotations)
+}
+
+// Records the user who added or changed the pause annotation, which usually does not change the spec
+func recordPausedBy(old, cr *opsv1alpha1.Application, username string) {
+	value, paused := cr.Annotations[opsv1alpha1.PausedAnnotation]
+	oldValue, wasPaused := old.Annotations[opsv1alpha1.PausedAnnotation]
+	switch {
+	case !paused:
+		delete(cr.Annotations, opsv1alpha1.PausedByAnnotation)
+	case !wasPaused || value != oldValue:
+		cr.Annotations[opsv1alpha1.PausedByAnnotation] = username
+	}
+}
+
+func patchResponse(req admission.Request, cr *opsv1alpha1.Application) admission.Response {
+	marshaled, err := json.Marshal(cr)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
@@ -0,0 +1,116 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/mdvorak/argo-application-operator/pkg/apis"
+	opsv1alpha1 "github.com/mdvorak/argo-application-operator/pkg/apis/ops/v1alpha1"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"strings"
+	"testing"
+)
+
+func TestPausedByIsRecordedWhenOnlyAnnotationChanges(t *testing.T) {
+	old := &opsv1alpha1.Application{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "guestbook",
+			Namespace: "foo",
+			Annotations: map[string]string{
+				opsv1alpha1.ModifiedByAnnotation: "author",
+			},
+		},
+	}
+	old.Spec.Source.RepoURL = "https://github.com/argoproj/argocd-example-apps"
+
+	tests := []struct {
+		name     string
+		old      map[string]string
+		new      map[string]string
+		expected string
+	}{
+		{"paused", nil, map[string]string{opsv1alpha1.PausedAnnotation: "whatever"}, "jdoe"},
+		{"pause changed", map[string]string{opsv1alpha1.PausedAnnotation: "a", opsv1alpha1.PausedByAnnotation: "author"}, map[string]string{opsv1alpha1.PausedAnnotation: "b"}, "jdoe"},
+		{"pause kept", map[string]string{opsv1alpha1.PausedAnnotation: "a", opsv1alpha1.PausedByAnnotation: "author"}, map[string]string{opsv1alpha1.PausedAnnotation: "a"}, "author"},
+		{"forged", map[string]string{opsv1alpha1.PausedAnnotation: "a", opsv1alpha1.PausedByAnnotation: "author"}, map[string]string{opsv1alpha1.PausedAnnotation: "a", opsv1alpha1.PausedByAnnotation: "admin"}, "author"},
+		{"resumed", map[string]string{opsv1alpha1.PausedAnnotation: "a", opsv1alpha1.PausedByAnnotation: "author"}, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldCR := old.DeepCopy()
+			for k, v := range tt.old {
+				oldCR.Annotations[k] = v
+			}
+			newCR := old.DeepCopy()
+			delete(newCR.Annotations, opsv1alpha1.PausedByAnnotation)
+			for k, v := range tt.new {
+				newCR.Annotations[k] = v
+			}
+
+			annotations := handle(t, oldCR, newCR, "jdoe")
+			if annotations[opsv1alpha1.PausedByAnnotation] != tt.expected {
+				t.Errorf("expected paused-by %q, got %q", tt.expected, annotations[opsv1alpha1.PausedByAnnotation])
+			}
+			if annotations[opsv1alpha1.ModifiedByAnnotation] != "author" {
+				t.Errorf("expected modified-by to be kept, got %q", annotations[opsv1alpha1.ModifiedByAnnotation])
+			}
+		})
+	}
+}
+
+// Sends update of the spec-unchanged object to the webhook, returns annotations of the patched object
+func handle(t *testing.T, old, cr *opsv1alpha1.Application, username string) map[string]string {
+	scheme := runtime.NewScheme()
+	if err := apis.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &applicationWebhook{}
+	if err := w.InjectDecoder(decoder); err != nil {
+		t.Fatal(err)
+	}
+
+	oldRaw, _ := json.Marshal(old)
+	raw, _ := json.Marshal(cr)
+	resp := w.Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+		Operation: admissionv1beta1.Update,
+		Object:    runtime.RawExtension{Raw: raw},
+		OldObject: runtime.RawExtension{Raw: oldRaw},
+		UserInfo:  authenticationv1.UserInfo{Username: username},
+	}})
+	if !resp.Allowed {
+		t.Fatalf("expected request to be allowed, got %v", resp.Result)
+	}
+
+	// Apply patch of annotations, webhook never touches anything else on unchanged spec
+	annotations := map[string]string{}
+	for k, v := range cr.Annotations {
+		annotations[k] = v
+	}
+	for _, op := range resp.Patches {
+		switch {
+		case op.Path == "/metadata/annotations":
+			annotations = map[string]string{}
+			for k, v := range op.Value.(map[string]interface{}) {
+				annotations[k] = v.(string)
+			}
+		case strings.HasPrefix(op.Path, "/metadata/annotations/"):
+			key := strings.NewReplacer("~1", "/", "~0", "~").Replace(strings.TrimPrefix(op.Path, "/metadata/annotations/"))
+			if op.Operation == "remove" {
+				delete(annotations, key)
+			} else {
+				annotations[key] = op.Value.(string)
+			}
+		default:
+			t.Errorf("unexpected patch %s %s", op.Operation, op.Path)
+		}
+	}
+	return annotations
+}
@@ -0,0 +1,53 @@
+package webhook
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+//noinspection GoUnusedConst
+const (
+	EnabledEnvVar  = "WEBHOOK_ENABLED"
+	PortEnvVar     = "WEBHOOK_PORT"
+	PortDefault    = 9443
+	CertDirEnvVar  = "WEBHOOK_CERT_DIR"
+	CertDirDefault = "/tmp/k8s-webhook-server/serving-certs"
+)
+
+// Returns true when admission webhooks should be served. They require serving certificate, so they are disabled
+// by default.
+func GetEnabled() (bool, error) {
+	if value, ok := os.LookupEnv(EnabledEnvVar); ok && len(value) > 0 {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return false, fmt.Errorf("%s is not a valid boolean: %w", EnabledEnvVar, err)
+		}
+		return enabled, nil
+	} else {
+		// Default
+		return false, nil
+	}
+}
+
+func GetPort() (int, error) {
+	if value, ok := os.LookupEnv(PortEnvVar); ok && len(value) > 0 {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("%s is not a valid port: %w", PortEnvVar, err)
+		}
+		return port, nil
+	} else {
+		// Default
+		return PortDefault, nil
+	}
+}
+
+func GetCertDir() string {
+	if value, ok := os.LookupEnv(CertDirEnvVar); ok && len(value) > 0 {
+		return value
+	} else {
+		// Default
+		return CertDirDefault
+	}
+}